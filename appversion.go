@@ -0,0 +1,92 @@
+package guesswho
+
+import (
+	"log"
+	"strconv"
+	"strings"
+)
+
+// Минимальная версия приложения: клиент объявляет свою версию query параметром
+// appVersion при подключении, и если она ниже Options.MinClientVersion (или не
+// объявлена вовсе), сервер вместо Connected отправляет UpgradeRequired со
+// ссылкой на скачивание и закрывает соединение. Так ломающие изменения
+// протокола выкатываются без старых клиентов, которые их не понимают
+
+type UpgradeRequired struct {
+	AppVersion  string `json:"appVersion,omitempty"` // что объявил клиент
+	MinVersion  string `json:"minVersion"`
+	DownloadURL string `json:"downloadUrl,omitempty"`
+}
+
+// parseAppVersion разбирает версию вида 1.4.2 или v1.4.2-beta, суффикс после
+// - или + не учитывается
+func parseAppVersion(raw string) ([]int, bool) {
+	raw = strings.TrimPrefix(raw, "v")
+	if i := strings.IndexAny(raw, "-+"); i >= 0 {
+		raw = raw[:i]
+	}
+	if raw == "" {
+		return nil, false
+	}
+
+	var parts []int
+	for _, part := range strings.Split(raw, ".") {
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 {
+			return nil, false
+		}
+		parts = append(parts, number)
+	}
+	return parts, true
+}
+
+// compareAppVersions сравнивает версии по частям, недостающие части - нули
+func compareAppVersions(a, b []int) int {
+	for i := range max(len(a), len(b)) {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// checkAppVersion возвращает UpgradeRequired, если клиент с версией appVersion
+// пускать нельзя, и nil, если можно; версию Options.MinClientVersion проверил NewServer
+func (s *Server) checkAppVersion(appVersion string) *UpgradeRequired {
+	if s.opts.MinClientVersion == "" {
+		return nil
+	}
+
+	minimum, _ := parseAppVersion(s.opts.MinClientVersion)
+	version, ok := parseAppVersion(appVersion)
+	if ok && compareAppVersions(version, minimum) >= 0 {
+		return nil
+	}
+
+	return &UpgradeRequired{
+		AppVersion:  appVersion,
+		MinVersion:  s.opts.MinClientVersion,
+		DownloadURL: s.opts.ClientDownloadURL,
+	}
+}
+
+// rejectOutdated отправляет UpgradeRequired и закрывает соединение, игрок при
+// этом не регистрируется на сервере
+func (p *Player) rejectOutdated(upgrade *UpgradeRequired) {
+	log.Printf("INFO: rejecting player %s with app version %q, minimum is %s", p.ID, upgrade.AppVersion, upgrade.MinVersion)
+
+	p.send(generateMsg(WsMessageTypeUpgradeRequired, Payload{Upgrade: upgrade}))
+
+	go writer(p)
+	disconnect(p, closeUpgradeRequired)
+}
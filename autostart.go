@@ -0,0 +1,55 @@
+package guesswho
+
+import (
+	"log"
+	"time"
+)
+
+// readyToAutoStart проверяет условия автостарта, вызывается из хаба
+func (l *Lobby) readyToAutoStart() bool {
+	return l.Settings.AutoStart && !l.InGame && len(l.Players) >= l.Settings.MaxPlayers && l.allReady()
+}
+
+// maybeAutoStart запускает отсчет автостарта, когда лобби заполнилось и все
+// готовы, и отменяет его, если условия перестали выполняться
+func (l *Lobby) maybeAutoStart() {
+	ready := l.readyToAutoStart()
+	running := l.autoStartTimer != nil
+	countdown := l.server.opts.AutoStartCountdown
+
+	switch {
+	case ready && !running:
+		var timer *time.Timer
+		timer = time.AfterFunc(countdown, func() {
+			l.do(func() {
+				// отсчет могли отменить, пока событие ждало очереди
+				if l.autoStartTimer == timer {
+					l.autoStart()
+				}
+			})
+		})
+		l.autoStartTimer = timer
+
+		log.Printf("INFO: auto start countdown started in lobby %s", l.ID)
+		l.broadcast(generateMsg(WsMessageTypeAutoStartCountdown, Payload{Lobby: l, CountdownSeconds: int(countdown.Seconds())}))
+	case !ready && running:
+		l.autoStartTimer.Stop()
+		l.autoStartTimer = nil
+
+		log.Printf("INFO: auto start countdown cancelled in lobby %s", l.ID)
+		l.broadcast(generateMsg(WsMessageTypeAutoStartCancelled, Payload{Lobby: l}))
+	}
+}
+
+func (l *Lobby) autoStart() {
+	l.autoStartTimer = nil
+
+	if !l.readyToAutoStart() {
+		l.broadcast(generateMsg(WsMessageTypeAutoStartCancelled, Payload{Lobby: l}))
+		return
+	}
+
+	if err := l.startGame(); err != nil {
+		log.Printf("ERROR: can't auto start game in lobby %s, error: %v", l.ID, err)
+	}
+}
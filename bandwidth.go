@@ -0,0 +1,146 @@
+package guesswho
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// учёт трафика соединения или лобби. Входящие и исходящие байты считаются в
+// разных окнах: рассылки клиенту не должны съедать лимит его собственных
+// сообщений
+type Bandwidth struct {
+	mu       sync.Mutex
+	bytesIn  int64
+	bytesOut int64
+	in       bandwidthWindow
+	out      bandwidthWindow
+}
+
+// секундное окно трафика в одну сторону
+type bandwidthWindow struct {
+	start time.Time
+	bytes int64
+}
+
+// wait возвращает, сколько ждать до конца окна, если в нем превышен capPerSecond
+func (w *bandwidthWindow) wait(now time.Time, capPerSecond int64) time.Duration {
+	elapsed := now.Sub(w.start)
+	if capPerSecond <= 0 || elapsed >= time.Second || w.bytes <= capPerSecond {
+		return 0
+	}
+	return time.Second - elapsed
+}
+
+type BandwidthStats struct {
+	BytesReceived int64 `json:"bytesReceived"`
+	BytesSent     int64 `json:"bytesSent"`
+}
+
+// record учитывает n байт и возвращает, сколько нужно подождать до конца
+// текущего секундного окна этого направления, если лимит capPerSecond превышен
+func (b *Bandwidth) record(n int, inbound bool, capPerSecond int64) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	window := &b.out
+	if inbound {
+		b.bytesIn += int64(n)
+		window = &b.in
+	} else {
+		b.bytesOut += int64(n)
+	}
+
+	now := time.Now()
+	if now.Sub(window.start) >= time.Second {
+		window.start = now
+		window.bytes = 0
+	}
+	window.bytes += int64(n)
+
+	return window.wait(now, capPerSecond)
+}
+
+// overCap возвращает, сколько ждать до конца текущего окна, если в нем уже
+// превышен лимит capPerSecond в любую сторону, ничего не учитывая
+func (b *Bandwidth) overCap(capPerSecond int64) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	return max(b.in.wait(now, capPerSecond), b.out.wait(now, capPerSecond))
+}
+
+func (b *Bandwidth) stats() BandwidthStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return BandwidthStats{
+		BytesReceived: b.bytesIn,
+		BytesSent:     b.bytesOut,
+	}
+}
+
+// recordTraffic учитывает сообщение игрока в его соединении, лобби и сервере и
+// возвращает ожидание только по лимиту самого соединения. Трафик лобби - в
+// основном рассылки всем участникам, ждать за него должен тот, чьи действия
+// их вызывают, а не каждый получатель, см. throttleLobby
+func recordTraffic(player *Player, n int, inbound bool) time.Duration {
+	player.server.Bandwidth.record(n, inbound, 0)
+
+	if lobby := player.lobby(); lobby != nil {
+		lobby.Bandwidth.record(n, inbound, 0)
+	}
+
+	return player.Bandwidth.record(n, inbound, player.server.opts.ConnBandwidthCap)
+}
+
+// throttleLobby придерживает действие игрока в лобби, пока лобби в текущем
+// окне превышает Options.LobbyBandwidthCap: рассылка этого действия уйдет всем
+// участникам. Вызывается из читающей горутины игрока до события хаба и в
+// счетчик троттлинга соединения не идет
+func throttleLobby(player *Player, lobby *Lobby) {
+	if wait := lobby.Bandwidth.overCap(player.server.opts.LobbyBandwidthCap); wait > 0 {
+		log.Printf("WARNING: lobby %s exceeded bandwidth cap, delaying action of player %s for %v", lobby.ID, player.ID, wait)
+		time.Sleep(wait)
+	}
+}
+
+// handleBandwidth отдает трафик по лобби и игрокам только admin API: в ответе
+// id всех лобби и игроков, поэтому CORS здесь не открываем
+func (s *Server) handleBandwidth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte(`{"error": "Метод не поддерживается"}`))
+		return
+	}
+
+	if !s.requireAdmin(w, r) {
+		return
+	}
+
+	response := struct {
+		Total   BandwidthStats            `json:"total"`
+		Lobbies map[string]BandwidthStats `json:"lobbies"`
+		Players map[string]BandwidthStats `json:"players"`
+	}{
+		Total:   s.Bandwidth.stats(),
+		Lobbies: make(map[string]BandwidthStats),
+		Players: make(map[string]BandwidthStats),
+	}
+
+	s.mu.Lock()
+	for id, lobby := range s.Lobbies {
+		response.Lobbies[id] = lobby.Bandwidth.stats()
+	}
+	for id, player := range s.Players {
+		response.Players[id] = player.Bandwidth.stats()
+	}
+	s.mu.Unlock()
+
+	json.NewEncoder(w).Encode(response)
+}
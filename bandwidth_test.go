@@ -0,0 +1,95 @@
+package guesswho
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// статистика трафика доступна только с токеном admin API и без CORS
+func TestBandwidthRequiresAdmin(t *testing.T) {
+	opts := DefaultOptions()
+	opts.AdminToken = "secret"
+	server := newTestServer(t, opts)
+
+	for _, tc := range []struct {
+		name          string
+		authorization string
+		status        int
+	}{
+		{"no token", "", http.StatusUnauthorized},
+		{"wrong token", "Bearer nope", http.StatusUnauthorized},
+		{"admin", "Bearer secret", http.StatusOK},
+	} {
+		req, err := http.NewRequest(http.MethodGet, server.url+"/bandwidth", nil)
+		if err != nil {
+			t.Fatalf("NewRequest: %v", err)
+		}
+		if tc.authorization != "" {
+			req.Header.Set("Authorization", tc.authorization)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET /bandwidth: %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != tc.status {
+			t.Errorf("%s: got status %d, want %d", tc.name, resp.StatusCode, tc.status)
+		}
+		if origin := resp.Header.Get("Access-Control-Allow-Origin"); origin != "" {
+			t.Errorf("%s: got Access-Control-Allow-Origin %q", tc.name, origin)
+		}
+	}
+}
+
+// хост забивает лобби рассылками сверх LobbyBandwidthCap: придерживают его
+// действия, а тихого участника за чужие рассылки не троттлят и не отключают
+func TestLobbyBandwidthCapThrottlesSender(t *testing.T) {
+	opts := DefaultOptions()
+	opts.LobbyBandwidthCap = 2000
+	opts.MaxBandwidthThrottles = 1
+	server := newTestServer(t, opts)
+
+	host := server.dial(t, "")
+	host.createLobby("BND001", 6)
+
+	members := make([]*testClient, 5)
+	for i := range members {
+		members[i] = server.dial(t, "")
+		members[i].joinLobby("BND001", fmt.Sprintf("member%d", i))
+		members[i].expect(WsMessageTypeLobbyJoined)
+	}
+	quiet := members[0]
+
+	const updates = 5
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range updates {
+			host.send(WsMessageTypeUpdateLobbySettings, fmt.Sprintf(`{"settings":{"turnTimerSeconds":%d,"gameMode":"Classic","characterPack":"default","maxPlayers":6}}`, 30+i))
+		}
+	}()
+
+	start := time.Now()
+	for range 10 {
+		quiet.send(WsMessageTypeTimeSync, `{"clientTime":1}`)
+		quiet.expect(WsMessageTypeTimeSync)
+		time.Sleep(50 * time.Millisecond)
+	}
+	<-done
+
+	for range updates {
+		host.expect(WsMessageTypeLobbySettingsUpdated)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("host's updates took %v, want them delayed by the lobby cap", elapsed)
+	}
+
+	for _, client := range append(members, host) {
+		client.send(WsMessageTypeRequestSync, "")
+		client.expect(WsMessageTypeSyncState)
+	}
+}
@@ -0,0 +1,39 @@
+package guesswho
+
+// Пакетная отправка: пока writer занят одним сообщением, в SendChan может
+// накопиться очередь, например, при рассылке снимков в начале игры. Клиентам
+// с протоколом 4 и выше такую очередь writer отправляет одним кадром - JSON
+// массивом сообщений в порядке отправки, каждое со своим seq. Одиночное
+// сообщение по-прежнему уходит объектом, фоновые сообщения в пакет не попадают
+
+// batch дополняет first сообщениями, которые уже ждут в SendChan, но не больше
+// Options.MaxBatchMessages сообщений и Options.MaxBatchBytes байт. Вызывается
+// только writer'ом, first уже с номером
+func (p *Player) batch(first []byte) []byte {
+	opts := &p.server.opts
+	if opts.MaxBatchMessages <= 1 || p.negotiated() < batchProtocolVersion || len(p.SendChan) == 0 {
+		return first
+	}
+
+	frame := make([]byte, 0, 2*len(first))
+	frame = append(frame, '[')
+	frame = append(frame, first...)
+	count := 1
+
+collect:
+	for count < opts.MaxBatchMessages && len(frame) < opts.MaxBatchBytes {
+		select {
+		case message := <-p.SendChan:
+			frame = append(frame, ',')
+			frame = append(frame, p.sequence.stamp(message)...)
+			count++
+		default:
+			break collect
+		}
+	}
+
+	if count == 1 {
+		return first
+	}
+	return append(frame, ']')
+}
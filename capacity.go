@@ -0,0 +1,75 @@
+package guesswho
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+type LoadTier string
+
+const (
+	LoadTierLow    LoadTier = "Low"
+	LoadTierMedium LoadTier = "Medium"
+	LoadTierHigh   LoadTier = "High"
+	LoadTierFull   LoadTier = "Full"
+)
+
+type Capacity struct {
+	LoadTier               LoadTier `json:"loadTier"`
+	OnlinePlayersCount     int      `json:"onlinePlayersCount"`
+	LobbiesCount           int      `json:"lobbiesCount"`
+	LobbyCreationThrottled bool     `json:"lobbyCreationThrottled"`
+	// оценка ожидания в быстром поиске в секундах по длине очереди и
+	// частоте недавних матчей, 0 - матчей давно не было и оценить не по чему
+	EstimatedWaitSeconds int `json:"estimatedWaitSeconds"`
+}
+
+func (s *Server) capacity() *Capacity {
+	s.mu.Lock()
+	capacity := &Capacity{
+		OnlinePlayersCount: len(s.Players),
+		LobbiesCount:       len(s.Lobbies),
+	}
+	s.mu.Unlock()
+
+	capacity.EstimatedWaitSeconds = s.matchmaker.estimatedWaitSeconds()
+
+	switch load := float64(capacity.OnlinePlayersCount) / float64(max(s.opts.SoftPlayerCapacity, 1)); {
+	case load >= 1:
+		capacity.LoadTier = LoadTierFull
+	case load >= 0.75:
+		capacity.LoadTier = LoadTierHigh
+	case load >= 0.4:
+		capacity.LoadTier = LoadTierMedium
+	default:
+		capacity.LoadTier = LoadTierLow
+	}
+	capacity.LobbyCreationThrottled = capacity.LoadTier == LoadTierFull
+
+	return capacity
+}
+
+// capacityNotifier периодически рассылает всем подключенным игрокам текущую нагрузку
+func (s *Server) capacityNotifier(ctx context.Context) {
+	ticker := time.NewTicker(s.opts.CapacityUpdateInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		msg := generateMsg(WsMessageTypeCapacityUpdated, Payload{Capacity: s.capacity()})
+
+		s.mu.Lock()
+		for _, player := range s.Players {
+			if !player.sendPresence(msg) {
+				log.Printf("WARNING: presence queue of player %s is full, skipping capacity update", player.ID)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
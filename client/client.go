@@ -0,0 +1,539 @@
+// Package client - Go клиент протокола GuessWhoServer для интеграционных тестов,
+// ботов и сторонних инструментов.
+//
+//	c, err := client.Connect(ctx, "ws://localhost:8080/ws")
+//	c.OnLobbyJoined(func(lobby *client.Lobby, player *client.Player) { ... })
+//	err = c.CreateLobby(ctx, client.PlayerProfile{Nickname: "host"}, nil, nil)
+//
+// Запросы ждут Ack или Error от сервера по requestId. Обработчики событий
+// вызываются из читающей горутины по порядку, поэтому не должны блокироваться
+// и не должны ждать ответов на запросы, сделанные из них же.
+//
+// Клиент следит за номерами сообщений и, заметив пропуск, сам отправляет
+// RequestSync, полное состояние приходит в OnSyncState
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// версия протокола клиента: конверт ошибок и Ack, полные лобби в событиях
+const protocolVersion = 2
+
+var ErrClosed = errors.New("client: connection closed")
+
+type options struct {
+	appVersion  string
+	resumeToken string
+	lastSeq     *uint64
+	locale      string
+}
+
+type Option func(*options)
+
+// WithAppVersion сообщает серверу версию приложения. Если сервер требует версию
+// новее, Connect вернет *Upgrade
+func WithAppVersion(version string) Option {
+	return func(o *options) { o.appVersion = version }
+}
+
+// WithResumeToken возвращает клиента на место прежнего соединения
+func WithResumeToken(token string) Option {
+	return func(o *options) { o.resumeToken = token }
+}
+
+// WithLastSeq вместе с WithResumeToken просит сервер дослать сообщения после
+// seq, которые прежнее соединение не получило, см. Client.LastSeq
+func WithLastSeq(seq uint64) Option {
+	return func(o *options) { o.lastSeq = &seq }
+}
+
+// WithLocale выбирает язык localizedMessage в ошибках
+func WithLocale(locale string) Option {
+	return func(o *options) { o.locale = locale }
+}
+
+type Client struct {
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+
+	player      *Player
+	resumeToken string
+	lastSeq     atomic.Uint64
+
+	nextID   atomic.Uint64
+	mu       sync.Mutex
+	pending  map[string]chan error
+	handlers map[string][]func(*Message)
+	timeSync chan timeSyncReply
+
+	done     chan struct{}
+	closeErr error
+}
+
+// Connect подключается к серверу и дожидается Connected
+func Connect(ctx context.Context, rawURL string, opts ...Option) (*Client, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("client: invalid url %s: %w", rawURL, err)
+	}
+	query := u.Query()
+	query.Set("protocolVersion", strconv.Itoa(protocolVersion))
+	if o.appVersion != "" {
+		query.Set("appVersion", o.appVersion)
+	}
+	if o.resumeToken != "" {
+		query.Set("resumeToken", o.resumeToken)
+	}
+	if o.lastSeq != nil {
+		query.Set("lastSeq", strconv.FormatUint(*o.lastSeq, 10))
+	}
+	if o.locale != "" {
+		query.Set("locale", o.locale)
+	}
+	u.RawQuery = query.Encode()
+
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("client: can't connect to %s: %w", u, err)
+	}
+
+	c := &Client{
+		conn:     conn,
+		pending:  make(map[string]chan error),
+		handlers: make(map[string][]func(*Message)),
+		timeSync: make(chan timeSyncReply, 1),
+		done:     make(chan struct{}),
+	}
+	c.On(MessageTypeTimeSync, c.onTimeSync)
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetReadDeadline(deadline)
+	}
+	var connected Message
+	err = conn.ReadJSON(&connected)
+	if err == nil && connected.Type == MessageTypeUpgradeRequired {
+		conn.Close()
+		if payload, err := connected.Decode(); err == nil && payload.Upgrade != nil {
+			return nil, payload.Upgrade
+		}
+		return nil, fmt.Errorf("client: server requires a newer app version")
+	}
+	if err != nil || connected.Type != MessageTypeConnected {
+		conn.Close()
+		return nil, fmt.Errorf("client: no Connected message from server, got %q: %v", connected.Type, err)
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	payload, err := connected.Decode()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	c.player = payload.Player
+	c.resumeToken = payload.ResumeToken
+
+	// сервер досылает пропущенное после lastSeq, иначе считаем с номера сессии
+	if o.lastSeq != nil && *o.lastSeq <= payload.LastSeq {
+		c.lastSeq.Store(*o.lastSeq)
+	} else {
+		c.lastSeq.Store(payload.LastSeq)
+	}
+
+	go c.readLoop()
+	return c, nil
+}
+
+// Player - игрок этого соединения, каким его прислал сервер в Connected
+func (c *Client) Player() *Player {
+	return c.player
+}
+
+// ResumeToken - секрет для возвращения на место после обрыва, см. WithResumeToken
+func (c *Client) ResumeToken() string {
+	return c.resumeToken
+}
+
+// LastSeq - номер последнего полученного сообщения, его передают в WithLastSeq
+// при переподключении
+func (c *Client) LastSeq() uint64 {
+	return c.lastSeq.Load()
+}
+
+// Done закрывается, когда соединение закрыто, причина - в Err
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *Client) Err() error {
+	select {
+	case <-c.done:
+		return c.closeErr
+	default:
+		return nil
+	}
+}
+
+// Close закрывает соединение с close frame, не выходя из лобби
+func (c *Client) Close() error {
+	c.writeMu.Lock()
+	c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	c.writeMu.Unlock()
+	return c.conn.Close()
+}
+
+// On подписывает handler на сообщения сервера типа msgType
+func (c *Client) On(msgType string, handler func(*Message)) {
+	c.mu.Lock()
+	c.handlers[msgType] = append(c.handlers[msgType], handler)
+	c.mu.Unlock()
+}
+
+// onLobby подписывает handler на событие лобби с игроком, которого оно касается
+func (c *Client) onLobby(msgType string, handler func(*Lobby, *Player)) {
+	c.On(msgType, func(msg *Message) {
+		if payload, err := msg.Decode(); err == nil {
+			handler(payload.Lobby, payload.Player)
+		}
+	})
+}
+
+func (c *Client) OnLobbyCreated(handler func(*Lobby, *Player)) {
+	c.onLobby(MessageTypeLobbyCreated, handler)
+}
+
+func (c *Client) OnLobbyJoined(handler func(*Lobby, *Player)) {
+	c.onLobby(MessageTypeLobbyJoined, handler)
+}
+
+func (c *Client) OnPlayerLeft(handler func(*Lobby, *Player)) {
+	c.onLobby(MessageTypePlayerLeft, handler)
+}
+
+func (c *Client) OnPlayerReadyChanged(handler func(*Lobby, *Player)) {
+	c.onLobby(MessageTypePlayerReadyChanged, handler)
+}
+
+func (c *Client) OnHostChanged(handler func(*Lobby, *Player)) {
+	c.onLobby(MessageTypeHostChanged, handler)
+}
+
+func (c *Client) OnGameStarted(handler func(*Lobby, *Player)) {
+	c.onLobby(MessageTypeGameStarted, handler)
+}
+
+func (c *Client) OnPlayerKicked(handler func(*Lobby, *Player)) {
+	c.onLobby(MessageTypePlayerKicked, handler)
+}
+
+// OnKickedFromLobby - хост выгнал этого игрока из лобби lobbyID
+func (c *Client) OnKickedFromLobby(handler func(lobbyID string)) {
+	c.On(MessageTypeKickedFromLobby, func(msg *Message) {
+		if payload, err := msg.Decode(); err == nil && payload.Lobby != nil {
+			handler(payload.Lobby.ID)
+		}
+	})
+}
+
+// OnLobbyClosed - сервер закрыл лобби, reason - Empty, Idle или Admin
+func (c *Client) OnLobbyClosed(handler func(lobbyID, reason string)) {
+	c.On(MessageTypeLobbyClosed, func(msg *Message) {
+		if payload, err := msg.Decode(); err == nil && payload.Lobby != nil {
+			handler(payload.Lobby.ID, payload.CloseReason)
+		}
+	})
+}
+
+func (c *Client) OnInvitationReceived(handler func(*Invitation)) {
+	c.On(MessageTypeInvitationReceived, func(msg *Message) {
+		if payload, err := msg.Decode(); err == nil {
+			handler(payload.Invitation)
+		}
+	})
+}
+
+func (c *Client) OnSyncState(handler func(*SyncState)) {
+	c.On(MessageTypeSyncState, func(msg *Message) {
+		if payload, err := msg.Decode(); err == nil {
+			handler(payload.Sync)
+		}
+	})
+}
+
+// OnError - ошибки, не связанные с запросами этого клиента
+func (c *Client) OnError(handler func(*Error)) {
+	c.On(MessageTypeError, func(msg *Message) {
+		handler(msg.asError())
+	})
+}
+
+func (m *Message) asError() *Error {
+	return &Error{Code: m.Code, RequestType: m.RequestType, Message: m.Message, LocalizedMessage: m.LocalizedMessage}
+}
+
+func (c *Client) readLoop() {
+	defer func() {
+		c.mu.Lock()
+		for id, result := range c.pending {
+			result <- ErrClosed
+			delete(c.pending, id)
+		}
+		c.mu.Unlock()
+		close(c.done)
+	}()
+
+	for {
+		var msg Message
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			c.closeErr = err
+			return
+		}
+
+		if msg.Seq != 0 {
+			c.checkSeq(&msg)
+		}
+
+		if msg.RequestID != "" && (msg.Type == MessageTypeAck || msg.Type == MessageTypeError) {
+			c.mu.Lock()
+			result, exists := c.pending[msg.RequestID]
+			delete(c.pending, msg.RequestID)
+			c.mu.Unlock()
+
+			if exists {
+				if msg.Type == MessageTypeError {
+					result <- msg.asError()
+				} else {
+					result <- nil
+				}
+				continue
+			}
+		}
+
+		c.mu.Lock()
+		handlers := c.handlers[msg.Type]
+		c.mu.Unlock()
+		for _, handler := range handlers {
+			handler(&msg)
+		}
+	}
+}
+
+// checkSeq запрашивает полное состояние, если между сообщениями пропущены
+// номера. SyncState сам по себе полное состояние, после него пропуск не важен
+func (c *Client) checkSeq(msg *Message) {
+	last := c.lastSeq.Swap(msg.Seq)
+	if msg.Seq <= last+1 || msg.Type == MessageTypeSyncState {
+		return
+	}
+
+	// ждать Ack здесь нельзя, его читает эта же горутина
+	c.writeMu.Lock()
+	err := c.conn.WriteJSON(struct {
+		Type string `json:"type"`
+	}{MessageTypeRequestSync})
+	c.writeMu.Unlock()
+	if err != nil {
+		c.conn.Close()
+	}
+}
+
+// Send отправляет запрос и ждет Ack; ошибка сервера возвращается как *Error
+func (c *Client) Send(ctx context.Context, msgType string, payload any) error {
+	id := strconv.FormatUint(c.nextID.Add(1), 10)
+	result := make(chan error, 1)
+
+	c.mu.Lock()
+	c.pending[id] = result
+	c.mu.Unlock()
+
+	request := struct {
+		Type      string `json:"type"`
+		RequestID string `json:"requestId"`
+		Payload   any    `json:"payload,omitempty"`
+	}{msgType, id, payload}
+
+	c.writeMu.Lock()
+	err := c.conn.WriteJSON(request)
+	c.writeMu.Unlock()
+	if err != nil {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return fmt.Errorf("client: can't send %s: %w", msgType, err)
+	}
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+type lobbyRef struct {
+	ID string `json:"id"`
+}
+
+type playerRef struct {
+	ID       string `json:"id,omitempty"`
+	Nickname string `json:"nickname,omitempty"`
+}
+
+// CreateLobby создает лобби, lobby и settings могут быть nil. Само лобби
+// приходит в OnLobbyCreated
+func (c *Client) CreateLobby(ctx context.Context, profile PlayerProfile, lobby *LobbyInfo, settings *LobbySettings) error {
+	return c.Send(ctx, MessageTypeCreateLobby, struct {
+		Player   PlayerProfile  `json:"player"`
+		Lobby    *LobbyInfo     `json:"lobby,omitempty"`
+		Settings *LobbySettings `json:"settings,omitempty"`
+	}{profile, lobby, settings})
+}
+
+// JoinLobby входит в лобби по коду, inviteToken нужен для закрытых лобби
+func (c *Client) JoinLobby(ctx context.Context, profile PlayerProfile, lobbyID, inviteToken string) error {
+	return c.Send(ctx, MessageTypeJoinLobby, struct {
+		Player      PlayerProfile `json:"player"`
+		Lobby       lobbyRef      `json:"lobby"`
+		InviteToken string        `json:"inviteToken,omitempty"`
+	}{profile, lobbyRef{lobbyID}, inviteToken})
+}
+
+func (c *Client) JoinAsSpectator(ctx context.Context, profile PlayerProfile, lobbyID string) error {
+	return c.Send(ctx, MessageTypeJoinAsSpectator, struct {
+		Player PlayerProfile `json:"player"`
+		Lobby  lobbyRef      `json:"lobby"`
+	}{profile, lobbyRef{lobbyID}})
+}
+
+func (c *Client) SetReady(ctx context.Context, ready bool) error {
+	if ready {
+		return c.Send(ctx, MessageTypePlayerReady, nil)
+	}
+	return c.Send(ctx, MessageTypePlayerUnready, nil)
+}
+
+func (c *Client) StartGame(ctx context.Context) error {
+	return c.Send(ctx, MessageTypeStartGame, nil)
+}
+
+// KickPlayer выгоняет игрока из лобби. ban действует, пока жива сессия игрока:
+// переподключившись без resume token, он получит новый ID и сможет войти снова
+func (c *Client) KickPlayer(ctx context.Context, playerID string, ban bool) error {
+	return c.Send(ctx, MessageTypeKickPlayer, struct {
+		Player playerRef `json:"player"`
+		Ban    bool      `json:"ban,omitempty"`
+	}{playerRef{ID: playerID}, ban})
+}
+
+func (c *Client) TransferHost(ctx context.Context, playerID string) error {
+	return c.Send(ctx, MessageTypeTransferHost, struct {
+		Player playerRef `json:"player"`
+	}{playerRef{ID: playerID}})
+}
+
+func (c *Client) UpdateLobbySettings(ctx context.Context, settings LobbySettings) error {
+	return c.Send(ctx, MessageTypeUpdateLobbySettings, struct {
+		Settings LobbySettings `json:"settings"`
+	}{settings})
+}
+
+func (c *Client) SetLocked(ctx context.Context, locked bool) error {
+	if locked {
+		return c.Send(ctx, MessageTypeLockLobby, nil)
+	}
+	return c.Send(ctx, MessageTypeUnlockLobby, nil)
+}
+
+func (c *Client) InvitePlayer(ctx context.Context, playerID, nickname string) error {
+	return c.Send(ctx, MessageTypeInvitePlayer, struct {
+		Player playerRef `json:"player"`
+	}{playerRef{playerID, nickname}})
+}
+
+func (c *Client) RespondToInvitation(ctx context.Context, invitationID string, accept bool) error {
+	msgType := MessageTypeDeclineInvitation
+	if accept {
+		msgType = MessageTypeAcceptInvitation
+	}
+	return c.Send(ctx, msgType, struct {
+		Invitation lobbyRef `json:"invitation"`
+	}{lobbyRef{invitationID}})
+}
+
+// RequestSync запрашивает полное состояние игрока, оно приходит в OnSyncState
+func (c *Client) RequestSync(ctx context.Context) error {
+	return c.Send(ctx, MessageTypeRequestSync, nil)
+}
+
+type timeSyncReply struct {
+	sync       *TimeSync
+	receivedAt time.Time
+}
+
+func (c *Client) onTimeSync(msg *Message) {
+	payload, err := msg.Decode()
+	if err != nil || payload.TimeSync == nil {
+		return
+	}
+	select {
+	case c.timeSync <- timeSyncReply{payload.TimeSync, time.Now()}:
+	default:
+	}
+}
+
+// SyncTime сверяет часы с сервером: offset - на сколько часы сервера спешат
+// относительно локальных, rtt - время туда и обратно этого обмена
+func (c *Client) SyncTime(ctx context.Context) (offset, rtt time.Duration, err error) {
+	// ответ на прошлый вызов, который не дождался своего
+	select {
+	case <-c.timeSync:
+	default:
+	}
+
+	sentAt := time.Now()
+	if err := c.Send(ctx, MessageTypeTimeSync, struct {
+		ClientTime int64 `json:"clientTime"`
+	}{sentAt.UnixMilli()}); err != nil {
+		return 0, 0, err
+	}
+
+	// TimeSync приходит раньше Ack, поэтому уже лежит в канале
+	var reply timeSyncReply
+	select {
+	case reply = <-c.timeSync:
+	default:
+		return 0, 0, fmt.Errorf("client: no TimeSync reply from server")
+	}
+
+	receivedAt := reply.receivedAt.UnixMilli()
+	serverReceivedAt, serverSentAt := reply.sync.ServerReceivedAt, reply.sync.ServerSentAt
+	offset = time.Duration((serverReceivedAt-reply.sync.ClientTime)+(serverSentAt-receivedAt)) * time.Millisecond / 2
+	rtt = time.Duration((receivedAt-reply.sync.ClientTime)-(serverSentAt-serverReceivedAt)) * time.Millisecond
+	return offset, rtt, nil
+}
+
+// Quit выходит из лобби и закрывает соединение на стороне сервера
+func (c *Client) Quit(ctx context.Context) error {
+	err := c.Send(ctx, MessageTypePlayerQuit, nil)
+	if errors.Is(err, ErrClosed) {
+		return nil
+	}
+	return err
+}
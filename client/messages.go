@@ -0,0 +1,104 @@
+// Code generated by tools/tsgen from the server message registries. DO NOT EDIT.
+
+package client
+
+// типы сообщений протокола, см. WsMessageType сервера
+const (
+	MessageTypeAcceptInvitation     = "AcceptInvitation"
+	MessageTypeAck                  = "Ack"
+	MessageTypeAutoStartCancelled   = "AutoStartCancelled"
+	MessageTypeAutoStartCountdown   = "AutoStartCountdown"
+	MessageTypeCancelFindMatch      = "CancelFindMatch"
+	MessageTypeCapacityUpdated      = "CapacityUpdated"
+	MessageTypeConnected            = "Connected"
+	MessageTypeConnectionQuality    = "ConnectionQuality"
+	MessageTypeCreateLobby          = "CreateLobby"
+	MessageTypeDeclineInvitation    = "DeclineInvitation"
+	MessageTypeError                = "Error"
+	MessageTypeFindMatch            = "FindMatch"
+	MessageTypeGameStarted          = "GameStarted"
+	MessageTypeGetLobbyEvents       = "GetLobbyEvents"
+	MessageTypeHello                = "Hello"
+	MessageTypeHostChanged          = "HostChanged"
+	MessageTypeInvitationReceived   = "InvitationReceived"
+	MessageTypeInvitationUpdated    = "InvitationUpdated"
+	MessageTypeInvitePlayer         = "InvitePlayer"
+	MessageTypeJoinAsSpectator      = "JoinAsSpectator"
+	MessageTypeJoinLobby            = "JoinLobby"
+	MessageTypeKickPlayer           = "KickPlayer"
+	MessageTypeKickedFromLobby      = "KickedFromLobby"
+	MessageTypeLeaveLobbyQueue      = "LeaveLobbyQueue"
+	MessageTypeLobbyClosed          = "LobbyClosed"
+	MessageTypeLobbyCreated         = "LobbyCreated"
+	MessageTypeLobbyEvents          = "LobbyEvents"
+	MessageTypeLobbyJoined          = "LobbyJoined"
+	MessageTypeLobbyLockChanged     = "LobbyLockChanged"
+	MessageTypeLobbyQueuePosition   = "LobbyQueuePosition"
+	MessageTypeLobbySettingsUpdated = "LobbySettingsUpdated"
+	MessageTypeLobbyStateDelta      = "LobbyStateDelta"
+	MessageTypeLockLobby            = "LockLobby"
+	MessageTypeMatchFound           = "MatchFound"
+	MessageTypeMatchmakingCancelled = "MatchmakingCancelled"
+	MessageTypeMatchmakingQueued    = "MatchmakingQueued"
+	MessageTypeMatchmakingTimedOut  = "MatchmakingTimedOut"
+	MessageTypePlayerDisconnected   = "PlayerDisconnected"
+	MessageTypePlayerKicked         = "PlayerKicked"
+	MessageTypePlayerLeft           = "PlayerLeft"
+	MessageTypePlayerQuit           = "PlayerQuit"
+	MessageTypePlayerReady          = "PlayerReady"
+	MessageTypePlayerReadyChanged   = "PlayerReadyChanged"
+	MessageTypePlayerReconnected    = "PlayerReconnected"
+	MessageTypePlayerTimedOut       = "PlayerTimedOut"
+	MessageTypePlayerUnready        = "PlayerUnready"
+	MessageTypeQueueForLobby        = "QueueForLobby"
+	MessageTypeRejoinLobby          = "RejoinLobby"
+	MessageTypeRequestSync          = "RequestSync"
+	MessageTypeSpectatorJoined      = "SpectatorJoined"
+	MessageTypeSpectatorsChanged    = "SpectatorsChanged"
+	MessageTypeStartGame            = "StartGame"
+	MessageTypeSyncState            = "SyncState"
+	MessageTypeTimeSync             = "TimeSync"
+	MessageTypeTransferHost         = "TransferHost"
+	MessageTypeUnlockLobby          = "UnlockLobby"
+	MessageTypeUpdateLobbySettings  = "UpdateLobbySettings"
+	MessageTypeUpgradeRequired      = "UpgradeRequired"
+)
+
+// коды ошибок сервера, см. Error.Code
+const (
+	ErrorCodeInternal            = "INTERNAL_ERROR"
+	ErrorCodeInvalidRequest      = "INVALID_REQUEST"
+	ErrorCodeUnknownMessageType  = "UNKNOWN_MESSAGE_TYPE"
+	ErrorCodeUnsupportedProtocol = "UNSUPPORTED_PROTOCOL_VERSION"
+	ErrorCodeRateLimited         = "RATE_LIMITED"
+	ErrorCodeMessageTooLarge     = "MESSAGE_TOO_LARGE"
+	ErrorCodeInvalidNickname     = "INVALID_NICKNAME"
+	ErrorCodeInvalidAvatar       = "INVALID_AVATAR"
+	ErrorCodeServerAtCapacity    = "SERVER_AT_CAPACITY"
+	ErrorCodeNicknameReserved    = "NICKNAME_RESERVED"
+	ErrorCodeAlreadyInLobby      = "ALREADY_IN_LOBBY"
+	ErrorCodeNotInLobby          = "NOT_IN_LOBBY"
+	ErrorCodeNotHost             = "NOT_HOST"
+	ErrorCodeLobbyNotFound       = "LOBBY_NOT_FOUND"
+	ErrorCodeLobbyFull           = "LOBBY_FULL"
+	ErrorCodeLobbyLocked         = "LOBBY_LOCKED"
+	ErrorCodeLobbyCodeTaken      = "LOBBY_CODE_TAKEN"
+	ErrorCodeInvalidLobbyCode    = "INVALID_LOBBY_CODE"
+	ErrorCodeInvalidLobbyInfo    = "INVALID_LOBBY_INFO"
+	ErrorCodeInvalidSettings     = "INVALID_SETTINGS"
+	ErrorCodeBanned              = "BANNED"
+	ErrorCodePlayerNotFound      = "PLAYER_NOT_FOUND"
+	ErrorCodeGameInProgress      = "GAME_IN_PROGRESS"
+	ErrorCodeNotEnoughPlayers    = "NOT_ENOUGH_PLAYERS"
+	ErrorCodePlayersNotReady     = "PLAYERS_NOT_READY"
+	ErrorCodeSpectatorAction     = "SPECTATOR_ACTION"
+	ErrorCodeSpectatorsDisabled  = "SPECTATORS_DISABLED"
+	ErrorCodeNotQueued           = "NOT_QUEUED"
+	ErrorCodeInvalidInviteToken  = "INVALID_INVITE_TOKEN"
+	ErrorCodeInvitationNotFound  = "INVITATION_NOT_FOUND"
+	ErrorCodeInvalidResumeToken  = "INVALID_RESUME_TOKEN"
+	ErrorCodeSeatNotReserved     = "SEAT_NOT_RESERVED"
+	ErrorCodeAmbiguousNickname   = "AMBIGUOUS_NICKNAME"
+	ErrorCodePlayerNotInvitable  = "PLAYER_NOT_INVITABLE"
+	ErrorCodePlayerDisconnected  = "PLAYER_DISCONNECTED"
+)
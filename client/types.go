@@ -0,0 +1,155 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// типы протокола с точки зрения клиента, JSON совпадает с тем, что шлет сервер
+
+type Player struct {
+	ID          string `json:"id,omitempty"`
+	Nickname    string `json:"nickname,omitempty"`
+	AvatarIdx   int    `json:"avatarIdx,omitempty"`
+	IsHost      bool   `json:"isHost,omitempty"`
+	IsReady     bool   `json:"isReady"`
+	IsSpectator bool   `json:"isSpectator,omitempty"`
+}
+
+type LobbySettings struct {
+	TurnTimerSeconds   int    `json:"turnTimerSeconds"`
+	GameMode           string `json:"gameMode"`
+	CharacterPack      string `json:"characterPack"`
+	MaxPlayers         int    `json:"maxPlayers"`
+	SpectatorsDisabled bool   `json:"spectatorsDisabled"`
+	AutoStart          bool   `json:"autoStart"`
+}
+
+type Lobby struct {
+	ID         string        `json:"id,omitempty"`
+	Name       string        `json:"name,omitempty"`
+	Region     string        `json:"region,omitempty"`
+	Players    []*Player     `json:"players,omitempty"`
+	Spectators []*Player     `json:"spectators,omitempty"`
+	IsPublic   bool          `json:"isPublic"`
+	IsLocked   bool          `json:"isLocked"`
+	InGame     bool          `json:"inGame"`
+	Settings   LobbySettings `json:"settings"`
+	Revision   uint64        `json:"revision"`
+}
+
+type Capacity struct {
+	LoadTier               string `json:"loadTier"`
+	OnlinePlayersCount     int    `json:"onlinePlayersCount"`
+	LobbiesCount           int    `json:"lobbiesCount"`
+	LobbyCreationThrottled bool   `json:"lobbyCreationThrottled"`
+	EstimatedWaitSeconds   int    `json:"estimatedWaitSeconds"`
+}
+
+type Invitation struct {
+	ID        string    `json:"id"`
+	LobbyID   string    `json:"lobbyId"`
+	From      *Player   `json:"from"`
+	To        *Player   `json:"to"`
+	Status    string    `json:"status"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type SyncState struct {
+	Player        *Player       `json:"player"`
+	Lobby         *Lobby        `json:"lobby,omitempty"`
+	QueuedLobbyID string        `json:"queuedLobbyId,omitempty"`
+	QueuePosition int           `json:"queuePosition,omitempty"`
+	Invitations   []*Invitation `json:"invitations,omitempty"` // ожидающие ответа, входящие и исходящие
+	Capacity      *Capacity     `json:"capacity,omitempty"`
+}
+
+// TimeSync - ответ сервера на TimeSync, отметки в миллисекундах Unix
+type TimeSync struct {
+	ClientTime       int64 `json:"clientTime"`
+	ServerReceivedAt int64 `json:"serverReceivedAt"`
+	ServerSentAt     int64 `json:"serverSentAt"`
+	RttMs            int64 `json:"rttMs"`
+}
+
+// Payload - payload сообщений сервера, заполнены только поля, относящиеся к типу сообщения
+type Payload struct {
+	Lobby            *Lobby         `json:"lobby,omitempty"`
+	Player           *Player        `json:"player,omitempty"`
+	Capacity         *Capacity      `json:"capacity,omitempty"`
+	Settings         *LobbySettings `json:"settings,omitempty"`
+	QueuePosition    int            `json:"queuePosition,omitempty"`
+	ResumeToken      string         `json:"resumeToken,omitempty"`
+	LastSeq          uint64         `json:"lastSeq,omitempty"`
+	CloseReason      string         `json:"closeReason,omitempty"`
+	CountdownSeconds int            `json:"countdownSeconds,omitempty"`
+	Invitation       *Invitation    `json:"invitation,omitempty"`
+	ProtocolVersion  int            `json:"protocolVersion,omitempty"`
+	Locale           string         `json:"locale,omitempty"`
+	Sync             *SyncState     `json:"sync,omitempty"`
+	TimeSync         *TimeSync      `json:"timeSync,omitempty"`
+	Upgrade          *Upgrade       `json:"upgrade,omitempty"`
+}
+
+// Message - сообщение сервера
+type Message struct {
+	Seq       uint64          `json:"seq,omitempty"` // 0 у фоновых сообщений
+	Type      string          `json:"type"`
+	RequestID string          `json:"requestId,omitempty"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+
+	// поля Error
+	Code             string `json:"code,omitempty"`
+	RequestType      string `json:"requestType,omitempty"`
+	Message          string `json:"message,omitempty"`
+	LocalizedMessage string `json:"localizedMessage,omitempty"`
+}
+
+// Decode разбирает payload сообщения
+func (m *Message) Decode() (*Payload, error) {
+	var payload Payload
+	if len(m.Payload) > 0 {
+		if err := json.Unmarshal(m.Payload, &payload); err != nil {
+			return nil, fmt.Errorf("client: can't decode %s payload: %w", m.Type, err)
+		}
+	}
+	return &payload, nil
+}
+
+// Upgrade - версия приложения устарела, сервер не пустил клиента
+type Upgrade struct {
+	AppVersion  string `json:"appVersion,omitempty"`
+	MinVersion  string `json:"minVersion"`
+	DownloadURL string `json:"downloadUrl,omitempty"`
+}
+
+func (u *Upgrade) Error() string {
+	return fmt.Sprintf("client: app version %q is outdated, minimum is %s, download from %s", u.AppVersion, u.MinVersion, u.DownloadURL)
+}
+
+// Error - ошибка, которой сервер ответил на запрос
+type Error struct {
+	Code             string
+	RequestType      string
+	Message          string
+	LocalizedMessage string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// PlayerProfile - ник и аватар, которые игрок сообщает о себе
+type PlayerProfile struct {
+	Nickname  string `json:"nickname"`
+	AvatarIdx int    `json:"avatarIdx,omitempty"`
+}
+
+// LobbyInfo - параметры создаваемого лобби, пустые поля выбирает сервер
+type LobbyInfo struct {
+	ID       string `json:"id,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Region   string `json:"region,omitempty"`
+	IsPublic bool   `json:"isPublic,omitempty"`
+}
@@ -0,0 +1,185 @@
+package guesswho
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maksec/GuessWhoServer/client"
+)
+
+// тесты Go клиента из пакета client против настоящего сервера
+
+func (s *testServer) connectClient(t *testing.T, opts ...client.Option) *client.Client {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c, err := client.Connect(ctx, "ws"+strings.TrimPrefix(s.url, "http")+"/ws", opts...)
+	if err != nil {
+		t.Fatalf("client.Connect: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// receive ждет значение из канала, куда его кладет обработчик клиента
+func receive[T any](t *testing.T, values <-chan T) T {
+	t.Helper()
+
+	select {
+	case value := <-values:
+		return value
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a client event")
+		panic("unreachable")
+	}
+}
+
+// lobbyEvents подписывает канал на событие лобби
+func lobbyEvents(subscribe func(func(*client.Lobby, *client.Player))) <-chan *client.Lobby {
+	events := make(chan *client.Lobby, 16)
+	subscribe(func(lobby *client.Lobby, _ *client.Player) { events <- lobby })
+	return events
+}
+
+func requestContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+// создание лобби, вход, готовность и старт игры
+func TestClientLobbyFlow(t *testing.T) {
+	server := newTestServer(t, DefaultOptions())
+	ctx := requestContext(t)
+
+	host := server.connectClient(t)
+	created := lobbyEvents(host.OnLobbyCreated)
+	if err := host.CreateLobby(ctx, client.PlayerProfile{Nickname: "host"}, &client.LobbyInfo{ID: "CLI001"}, nil); err != nil {
+		t.Fatalf("CreateLobby: %v", err)
+	}
+	if lobby := receive(t, created); lobby.ID != "CLI001" || len(lobby.Players) != 1 || !lobby.Players[0].IsHost {
+		t.Fatalf("got lobby %+v", lobby)
+	}
+
+	guest := server.connectClient(t)
+	joined := lobbyEvents(guest.OnLobbyJoined)
+	if err := guest.JoinLobby(ctx, client.PlayerProfile{Nickname: "guest"}, "CLI001", ""); err != nil {
+		t.Fatalf("JoinLobby: %v", err)
+	}
+	if lobby := receive(t, joined); len(lobby.Players) != 2 || lobby.Players[1].ID != guest.Player().ID {
+		t.Fatalf("got lobby %+v", lobby)
+	}
+
+	var clientErr *client.Error
+	if err := host.StartGame(ctx); !errors.As(err, &clientErr) || clientErr.Code != client.ErrorCodePlayersNotReady {
+		t.Fatalf("got %v, want %s", err, client.ErrorCodePlayersNotReady)
+	}
+
+	readyChanged := lobbyEvents(host.OnPlayerReadyChanged)
+	if err := guest.SetReady(ctx, true); err != nil {
+		t.Fatalf("SetReady: %v", err)
+	}
+	if lobby := receive(t, readyChanged); !lobby.Players[1].IsReady {
+		t.Errorf("guest is not ready in %+v", lobby.Players[1])
+	}
+
+	started := lobbyEvents(guest.OnGameStarted)
+	if err := host.StartGame(ctx); err != nil {
+		t.Fatalf("StartGame: %v", err)
+	}
+	if lobby := receive(t, started); !lobby.InGame {
+		t.Errorf("got lobby %+v, want a game in progress", lobby)
+	}
+
+	if _, _, err := guest.SyncTime(ctx); err != nil {
+		t.Errorf("SyncTime: %v", err)
+	}
+}
+
+// ошибки сервера возвращаются из запросов как *client.Error
+func TestClientErrors(t *testing.T) {
+	server := newTestServer(t, DefaultOptions())
+	ctx := requestContext(t)
+
+	c := server.connectClient(t, client.WithLocale("ru"))
+
+	err := c.JoinLobby(ctx, client.PlayerProfile{Nickname: "guest"}, "NOPE00", "")
+	var clientErr *client.Error
+	if !errors.As(err, &clientErr) {
+		t.Fatalf("got %v, want *client.Error", err)
+	}
+	if clientErr.Code != client.ErrorCodeLobbyNotFound || clientErr.RequestType != client.MessageTypeJoinLobby || clientErr.LocalizedMessage == "" {
+		t.Errorf("got error %+v", clientErr)
+	}
+
+	if err := c.SetReady(ctx, true); !errors.As(err, &clientErr) || clientErr.Code != client.ErrorCodeNotInLobby {
+		t.Errorf("got %v, want %s", err, client.ErrorCodeNotInLobby)
+	}
+
+	if err := c.Quit(ctx); err != nil {
+		t.Fatalf("Quit: %v", err)
+	}
+	<-c.Done()
+	if err := c.RequestSync(ctx); err == nil {
+		t.Error("request on a closed connection succeeded")
+	}
+}
+
+// клиент без close frame теряет связь и возвращается на свое место
+func TestClientResume(t *testing.T) {
+	server := newTestServer(t, DefaultOptions())
+	ctx := requestContext(t)
+
+	host := server.connectClient(t)
+	if err := host.CreateLobby(ctx, client.PlayerProfile{Nickname: "host"}, &client.LobbyInfo{ID: "CLI002"}, nil); err != nil {
+		t.Fatalf("CreateLobby: %v", err)
+	}
+
+	guest := server.connectClient(t)
+	if err := guest.JoinLobby(ctx, client.PlayerProfile{Nickname: "guest"}, "CLI002", ""); err != nil {
+		t.Fatalf("JoinLobby: %v", err)
+	}
+	guestID := guest.Player().ID
+
+	left := make(chan string, 1)
+	host.On(client.MessageTypePlayerDisconnected, func(*client.Message) { left <- "" })
+	guest.Close()
+	receive(t, left)
+
+	resumed := server.connectClient(t, client.WithResumeToken(guest.ResumeToken()), client.WithLastSeq(guest.LastSeq()))
+	if resumed.Player().ID != guestID {
+		t.Fatalf("got id %s, want %s", resumed.Player().ID, guestID)
+	}
+
+	synced := make(chan *client.SyncState, 1)
+	resumed.OnSyncState(func(state *client.SyncState) { synced <- state })
+	if err := resumed.RequestSync(ctx); err != nil {
+		t.Fatalf("RequestSync: %v", err)
+	}
+	state := receive(t, synced)
+	if state.Lobby == nil || state.Lobby.ID != "CLI002" || len(state.Lobby.Players) != 2 {
+		t.Fatalf("got lobby %+v after resume", state.Lobby)
+	}
+}
+
+// сервер не пускает устаревшее приложение, Connect возвращает *client.Upgrade
+func TestClientUpgradeRequired(t *testing.T) {
+	opts := DefaultOptions()
+	opts.MinClientVersion = "2.0.0"
+	opts.ClientDownloadURL = "https://example.com/download"
+	server := newTestServer(t, opts)
+
+	_, err := client.Connect(requestContext(t), "ws"+strings.TrimPrefix(server.url, "http")+"/ws", client.WithAppVersion("1.0.0"))
+	var upgrade *client.Upgrade
+	if !errors.As(err, &upgrade) {
+		t.Fatalf("got %v, want *client.Upgrade", err)
+	}
+	if upgrade.MinVersion != "2.0.0" || upgrade.DownloadURL != opts.ClientDownloadURL {
+		t.Errorf("got upgrade %+v", upgrade)
+	}
+}
@@ -0,0 +1,90 @@
+package guesswho
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// closeReason - почему сервер закрывает соединение. Code и Text уходят клиенту
+// в close frame, чтобы он мог отличить, например, замену сессии от обрыва сети.
+// Code == 0 - соединение уже оборвано или закрыто клиентом, close frame не шлем
+type closeReason struct {
+	Code int
+	Text string
+}
+
+// коды 4000-4999 - коды приложения, остальные из RFC 6455
+const (
+	closeCodeSessionReplaced = 4000
+	closeCodeIdleTimeout     = 4001
+	closeCodeUpgradeRequired = 4002
+)
+
+var (
+	closePlayerQuit      = closeReason{websocket.CloseNormalClosure, "PlayerQuit"}
+	closeSessionReplaced = closeReason{closeCodeSessionReplaced, "SessionReplaced"}
+	closeIdleTimeout     = closeReason{closeCodeIdleTimeout, "IdleTimeout"}
+	closeUpgradeRequired = closeReason{closeCodeUpgradeRequired, "UpgradeRequired"}
+	closeRateLimited     = closeReason{websocket.ClosePolicyViolation, "RateLimited"}
+	closeBandwidth       = closeReason{websocket.ClosePolicyViolation, "BandwidthExceeded"}
+	closeServerShutdown  = closeReason{websocket.CloseGoingAway, "ServerShutdown"}
+
+	closeByClient      = closeReason{Text: "ClosedByClient"}
+	closeConnectionErr = closeReason{Text: "ConnectionError"}
+	closeWriteFailed   = closeReason{Text: "WriteFailed"}
+	closePingFailed    = closeReason{Text: "PingFailed"}
+)
+
+// readCloseReason разбирает ошибку ReadMessage: клиент закрыл соединение
+// сам, замолчал дольше Options.PongWait или соединение оборвалось
+func readCloseReason(err error) closeReason {
+	var closeErr *websocket.CloseError
+	switch {
+	case errors.As(err, &closeErr) && closeErr.Code != websocket.CloseAbnormalClosure:
+		log.Printf("INFO: client closed connection, code %d: %s", closeErr.Code, closeErr.Text)
+		return closeByClient
+	case isIdleTimeout(err):
+		return closeIdleTimeout
+	default:
+		log.Printf("ERROR: can't read message (conn.ReadMessage()), error: %v", err)
+		return closeConnectionErr
+	}
+}
+
+// closeConnection вызывается writer'ом после disconnect: дописывает то, что
+// уже лежит в SendChan (например, ошибку, из-за которой отключаем), и
+// отправляет close frame. Соединение закрывает читающая горутина, когда
+// получит ответный close frame или истечет дедлайн чтения
+func closeConnection(player *Player) {
+	writeWait := player.server.opts.WriteWait
+	reason := player.closeReason
+	if reason.Code == 0 {
+		player.Conn.Close()
+		return
+	}
+
+	for drained := false; !drained; {
+		select {
+		case message := <-player.SendChan:
+			if !writeMessage(player, player.sequence.stamp(message)) {
+				player.Conn.Close()
+				return
+			}
+		default:
+			drained = true
+		}
+	}
+
+	closeMsg := websocket.FormatCloseMessage(reason.Code, reason.Text)
+	err := player.Conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(writeWait))
+	if err != nil && !errors.Is(err, websocket.ErrCloseSent) {
+		log.Printf("WARNING: can't send close frame to player %s, error: %v", player.id(), err)
+		player.Conn.Close()
+		return
+	}
+
+	player.Conn.SetReadDeadline(time.Now().Add(writeWait))
+}
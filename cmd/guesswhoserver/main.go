@@ -0,0 +1,106 @@
+// guesswhoserver - сервер GuessWho с настройками из флагов командной строки
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	guesswho "github.com/maksec/GuessWhoServer"
+)
+
+func main() {
+	opts := guesswho.DefaultOptions()
+
+	flag.Int64Var(&opts.ConnBandwidthCap, "conn-bandwidth-cap", opts.ConnBandwidthCap, "max bytes per second per connection, 0 disables the cap")
+	flag.Int64Var(&opts.LobbyBandwidthCap, "lobby-bandwidth-cap", opts.LobbyBandwidthCap, "max bytes per second per lobby, actions in a busier lobby are delayed, 0 disables the cap")
+	flag.IntVar(&opts.MaxBandwidthThrottles, "max-bandwidth-throttles", opts.MaxBandwidthThrottles, "consecutive inbound messages over conn-bandwidth-cap before disconnect")
+	flag.Float64Var(&opts.MessageRate, "message-rate", opts.MessageRate, "average inbound messages per second per connection")
+	flag.IntVar(&opts.MessageBurst, "message-burst", opts.MessageBurst, "inbound messages a connection may send in a burst")
+	flag.IntVar(&opts.MaxRateLimitedMsgs, "max-rate-limited-messages", opts.MaxRateLimitedMsgs, "consecutive rate limited messages before disconnect")
+	flag.Int64Var(&opts.MaxMessageSize, "max-message-size", opts.MaxMessageSize, "max inbound websocket frame size in bytes, bigger frames close the connection")
+	flag.IntVar(&opts.SoftPlayerCapacity, "soft-player-capacity", opts.SoftPlayerCapacity, "online players at which lobby creation is throttled")
+	flag.DurationVar(&opts.CapacityUpdateInterval, "capacity-update-interval", opts.CapacityUpdateInterval, "how often capacity updates are sent to connected players")
+	flag.StringVar(&opts.AdminToken, "admin-token", "", "bearer token for admin API, empty disables admin API")
+	reservedNicknamesFile := flag.String("reserved-nicknames-file", "", "file with additional reserved nicknames, one per line")
+	metaFile := flag.String("meta-file", "", "JSON file with deployment branding and rules served on /meta")
+	flag.DurationVar(&opts.ConnectionQualityInterval, "connection-quality-interval", opts.ConnectionQualityInterval, "how often clients are pinged and ConnectionQuality is sent")
+	flag.IntVar(&opts.MaxBatchMessages, "max-batch-messages", opts.MaxBatchMessages, "max queued messages sent in one frame to protocol 4 clients, 1 disables batching")
+	flag.IntVar(&opts.MaxBatchBytes, "max-batch-bytes", opts.MaxBatchBytes, "frame size after which no more queued messages are added to a batch")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 10*time.Second, "how long shutdown waits for connections to close")
+	flag.DurationVar(&opts.WriteWait, "write-wait", opts.WriteWait, "how long a single write to a client may take before the connection is dropped")
+	flag.DurationVar(&opts.PongWait, "pong-wait", opts.PongWait, "how long a connection that sends no messages and answers no pings is kept before it times out")
+	flag.DurationVar(&opts.MatchmakingTimeout, "matchmaking-timeout", opts.MatchmakingTimeout, "how long a player waits in the matchmaking queue before timing out")
+	flag.DurationVar(&opts.RegionPreferenceWindow, "region-preference-window", opts.RegionPreferenceWindow, "how long matchmaking prefers opponents from the same region")
+	flag.DurationVar(&opts.LobbyEmptyTTL, "lobby-empty-ttl", opts.LobbyEmptyTTL, "how long an empty lobby is kept before it is closed")
+	flag.DurationVar(&opts.LobbyIdleTTL, "lobby-idle-ttl", opts.LobbyIdleTTL, "how long a lobby without activity is kept before it is closed")
+	flag.DurationVar(&opts.JanitorInterval, "janitor-interval", opts.JanitorInterval, "how often the janitor looks for lobbies to close")
+	flag.DurationVar(&opts.IdempotencyKeyTTL, "idempotency-key-ttl", opts.IdempotencyKeyTTL, "how long the response to a request with an idempotency key is kept for retries")
+	flag.DurationVar(&opts.InviteTokenTTL, "invite-token-ttl", opts.InviteTokenTTL, "how long a lobby invite token stays valid")
+	flag.IntVar(&opts.ReplayBufferSize, "replay-buffer-size", opts.ReplayBufferSize, "how many recent messages per session are kept for replay on reconnect, 0 disables")
+	flag.DurationVar(&opts.RejoinGracePeriod, "rejoin-grace-period", opts.RejoinGracePeriod, "how long a disconnected player's lobby seat is held, 0 disables")
+	flag.DurationVar(&opts.AutoStartCountdown, "auto-start-countdown", opts.AutoStartCountdown, "countdown before a full, ready lobby with autoStart starts the game")
+	flag.DurationVar(&opts.PlayerInvitationTTL, "player-invitation-ttl", opts.PlayerInvitationTTL, "how long an invitation to an online player stays pending")
+	flag.IntVar(&opts.MaxLobbyPlayers, "max-lobby-players", opts.MaxLobbyPlayers, "upper bound for a lobby's maxPlayers setting")
+	flag.StringVar(&opts.MinClientVersion, "min-client-version", "", "oldest app version allowed to connect, empty disables the check")
+	flag.StringVar(&opts.ClientDownloadURL, "client-download-url", "", "where outdated clients download a new version, sent in UpgradeRequired")
+	flag.IntVar(&opts.AvatarsCount, "avatars-count", opts.AvatarsCount, "number of avatars clients can pick from")
+	flag.Parse()
+
+	if *reservedNicknamesFile != "" {
+		names, err := guesswho.LoadReservedNicknames(*reservedNicknamesFile)
+		if err != nil {
+			log.Fatalf("ERROR: can't load reserved nicknames file %s, error: %v", *reservedNicknamesFile, err)
+		}
+		opts.ReservedNicknames = names
+	}
+
+	if *metaFile != "" {
+		meta, err := guesswho.LoadMeta(*metaFile)
+		if err != nil {
+			log.Fatalf("ERROR: can't load meta file %s, error: %v", *metaFile, err)
+		}
+		opts.Meta = meta
+	}
+
+	server, err := guesswho.NewServer(opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	httpServer := &http.Server{Addr: ":8080", Handler: server}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.ListenAndServe()
+	}()
+	log.Println("Сервер запущен на :8080")
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	select {
+	case err := <-serveErr:
+		log.Fatal(err)
+	case sig := <-signals:
+		log.Printf("INFO: got %v", sig)
+	}
+
+	log.Printf("INFO: shutting down, waiting up to %v for connections to close", *shutdownTimeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+
+	// сначала игроки: пока открыты SSE потоки, HTTP сервер не остановится
+	server.Shutdown(ctx)
+
+	if err := httpServer.Shutdown(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("WARNING: can't shut down HTTP server gracefully, error: %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package guesswho
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"unicode/utf8"
+)
+
+const (
+	lobbyCodeLength      = 6
+	lobbyCodeAlphabet    = "ABCDEFGHJKMNPQRSTUVWXYZ23456789" // без 0/O, 1/I/L
+	lobbyCodeMaxRetries  = 10
+	minLobbyCodeLength   = 4
+	maxLobbyCodeLength   = 12
+	maxLobbyNameLength   = 32
+	maxLobbyRegionLength = 16
+)
+
+// generateLobbyCode генерирует свободный код лобби, вызывается под s.mu
+func (s *Server) generateLobbyCode() (string, error) {
+	alphabetSize := big.NewInt(int64(len(lobbyCodeAlphabet)))
+
+	for range lobbyCodeMaxRetries {
+		code := make([]byte, lobbyCodeLength)
+		for i := range code {
+			idx, err := rand.Int(rand.Reader, alphabetSize)
+			if err != nil {
+				return "", fmt.Errorf("ERROR: can't generate lobby code, error: %v", err)
+			}
+			code[i] = lobbyCodeAlphabet[idx.Int64()]
+		}
+
+		if _, exists := s.Lobbies[string(code)]; !exists {
+			return string(code), nil
+		}
+	}
+
+	return "", fmt.Errorf("ERROR: can't generate a free lobby code after %d retries", lobbyCodeMaxRetries)
+}
+
+// validateLobbyCode проверяет код лобби, выбранный хостом
+func validateLobbyCode(code string) error {
+	if len(code) < minLobbyCodeLength || len(code) > maxLobbyCodeLength {
+		return protocolError(ErrorCodeInvalidLobbyCode, "ERROR: lobby code must be %d to %d characters long", minLobbyCodeLength, maxLobbyCodeLength)
+	}
+
+	for _, c := range code {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return protocolError(ErrorCodeInvalidLobbyCode, "ERROR: lobby code may contain only A-Z and 0-9, got %q", c)
+		}
+	}
+
+	return nil
+}
+
+// validateLobbyMetadata проверяет отображаемое имя и регион лобби
+func validateLobbyMetadata(name, region string) error {
+	if utf8.RuneCountInString(name) > maxLobbyNameLength {
+		return protocolError(ErrorCodeInvalidLobbyInfo, "ERROR: lobby name must be at most %d characters long", maxLobbyNameLength)
+	}
+
+	if len(region) > maxLobbyRegionLength {
+		return protocolError(ErrorCodeInvalidLobbyInfo, "ERROR: lobby region must be at most %d characters long", maxLobbyRegionLength)
+	}
+
+	for _, c := range region {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+			return protocolError(ErrorCodeInvalidLobbyInfo, "ERROR: lobby region may contain only a-z, 0-9 and '-', got %q", c)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package guesswho
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+)
+
+// Дельты состояния лобби для клиентов с protocolVersion >= deltaProtocolVersion.
+// Перед каждой рассылкой лобби сравнивает свое состояние с последним
+// опубликованным и, если оно изменилось, увеличивает revision и рассылает
+// LobbyStateDelta с измененными полями. В самих событиях такие клиенты получают
+// вместо полного lobby только {id, revision}. Исключение - игрок, который сам
+// вошел в лобби или вернулся в него: он получает LobbyJoined или
+// PlayerReconnected с полным лобби, см. broadcastSnapshot. Если revision в
+// дельте не следует за известной клиенту, он пропустил сообщение и
+// запрашивает RequestSync
+
+type LobbyStateDelta struct {
+	LobbyID      string                     `json:"lobbyId"`
+	BaseRevision uint64                     `json:"baseRevision"`
+	Revision     uint64                     `json:"revision"`
+	Changes      map[string]json.RawMessage `json:"changes"` // поле лобби -> новое значение, null - поля больше нет
+}
+
+// publishDelta вычисляет дельту с последней опубликованной версии лобби,
+// nil - ничего не изменилось
+func (l *Lobby) publishDelta() []byte {
+	raw, err := json.Marshal(l)
+	if err != nil {
+		log.Printf("ERROR: failed marshal JSON: lobby %s, error: %v", l.ID, err)
+		return nil
+	}
+
+	var state map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &state); err != nil {
+		log.Printf("ERROR: can't unmarshal lobby %s state, error: %v", l.ID, err)
+		return nil
+	}
+	delete(state, "revision")
+
+	changes := make(map[string]json.RawMessage)
+	for field, value := range state {
+		if !bytes.Equal(l.published[field], value) {
+			changes[field] = value
+		}
+	}
+	for field := range l.published {
+		if _, exists := state[field]; !exists {
+			changes[field] = json.RawMessage("null")
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+
+	delta := LobbyStateDelta{
+		LobbyID:      l.ID,
+		BaseRevision: l.Revision,
+		Revision:     l.Revision + 1,
+		Changes:      changes,
+	}
+	l.Revision++
+	l.published = state
+
+	msg, err := json.Marshal(struct {
+		Type    WsMessageType   `json:"type"`
+		Payload LobbyStateDelta `json:"payload"`
+	}{
+		Type:    WsMessageTypeLobbyStateDelta,
+		Payload: delta,
+	})
+	if err != nil {
+		log.Printf("ERROR: failed marshal JSON: lobby %s delta, error: %v", l.ID, err)
+		return nil
+	}
+	return msg
+}
+
+// compactLobbyMsg заменяет полный lobby в payload события на {id, revision}
+func compactLobbyMsg(msg []byte, lobbyID string, revision uint64) []byte {
+	var message struct {
+		Type      WsMessageType              `json:"type"`
+		RequestID string                     `json:"requestId,omitempty"`
+		Payload   map[string]json.RawMessage `json:"payload"`
+	}
+	if err := json.Unmarshal(msg, &message); err != nil {
+		return msg
+	}
+	if _, exists := message.Payload["lobby"]; !exists {
+		return msg
+	}
+
+	ref, _ := json.Marshal(struct {
+		ID       string `json:"id"`
+		Revision uint64 `json:"revision"`
+	}{lobbyID, revision})
+	message.Payload["lobby"] = ref
+
+	compact, err := json.Marshal(message)
+	if err != nil {
+		return msg
+	}
+	return compact
+}
@@ -0,0 +1,56 @@
+package guesswho
+
+import (
+	"fmt"
+
+	"github.com/gorilla/websocket"
+)
+
+// Encoding - формат кадров соединения, выбирается при подключении query
+// параметром encoding; по умолчанию JSON
+type Encoding string
+
+const (
+	EncodingJSON    Encoding = "json"
+	EncodingMsgpack Encoding = "msgpack" // бинарные кадры, см. msgpack.go
+)
+
+func parseEncoding(raw string) (Encoding, error) {
+	switch Encoding(raw) {
+	case "", EncodingJSON:
+		return EncodingJSON, nil
+	case EncodingMsgpack:
+		return EncodingMsgpack, nil
+	}
+	return EncodingJSON, protocolError(ErrorCodeInvalidRequest, "ERROR: unknown encoding %s, supported are %s and %s", raw, EncodingJSON, EncodingMsgpack)
+}
+
+// encode превращает JSON сообщение в кадр соединения
+func (e Encoding) encode(msg []byte) (int, []byte, error) {
+	if e != EncodingMsgpack {
+		return websocket.TextMessage, msg, nil
+	}
+
+	frame, err := jsonToMsgpack(msg)
+	if err != nil {
+		return 0, nil, fmt.Errorf("ERROR: can't encode message to msgpack, error: %v", err)
+	}
+	return websocket.BinaryMessage, frame, nil
+}
+
+// decode превращает кадр соединения в JSON для read loop
+func (e Encoding) decode(frameType int, frame []byte) ([]byte, error) {
+	if e != EncodingMsgpack {
+		return frame, nil
+	}
+
+	if frameType != websocket.BinaryMessage {
+		return nil, protocolError(ErrorCodeInvalidRequest, "ERROR: msgpack connection expects binary frames")
+	}
+
+	msg, err := msgpackToJSON(frame)
+	if err != nil {
+		return nil, protocolError(ErrorCodeInvalidRequest, "ERROR: can't decode msgpack message: %v", err)
+	}
+	return msg, nil
+}
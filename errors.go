@@ -0,0 +1,110 @@
+package guesswho
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrorCode - стабильный машиночитаемый код ошибки, клиенты ветвятся по нему,
+// а не по тексту сообщения
+type ErrorCode string
+
+const (
+	ErrorCodeInternal            ErrorCode = "INTERNAL_ERROR"
+	ErrorCodeInvalidRequest      ErrorCode = "INVALID_REQUEST"
+	ErrorCodeUnknownMessageType  ErrorCode = "UNKNOWN_MESSAGE_TYPE"
+	ErrorCodeUnsupportedProtocol ErrorCode = "UNSUPPORTED_PROTOCOL_VERSION"
+	ErrorCodeRateLimited         ErrorCode = "RATE_LIMITED"
+	ErrorCodeMessageTooLarge     ErrorCode = "MESSAGE_TOO_LARGE"
+	ErrorCodeInvalidNickname     ErrorCode = "INVALID_NICKNAME"
+	ErrorCodeInvalidAvatar       ErrorCode = "INVALID_AVATAR"
+	ErrorCodeServerAtCapacity    ErrorCode = "SERVER_AT_CAPACITY"
+	ErrorCodeNicknameReserved    ErrorCode = "NICKNAME_RESERVED"
+	ErrorCodeAlreadyInLobby      ErrorCode = "ALREADY_IN_LOBBY"
+	ErrorCodeNotInLobby          ErrorCode = "NOT_IN_LOBBY"
+	ErrorCodeNotHost             ErrorCode = "NOT_HOST"
+	ErrorCodeLobbyNotFound       ErrorCode = "LOBBY_NOT_FOUND"
+	ErrorCodeLobbyFull           ErrorCode = "LOBBY_FULL"
+	ErrorCodeLobbyLocked         ErrorCode = "LOBBY_LOCKED"
+	ErrorCodeLobbyCodeTaken      ErrorCode = "LOBBY_CODE_TAKEN"
+	ErrorCodeInvalidLobbyCode    ErrorCode = "INVALID_LOBBY_CODE"
+	ErrorCodeInvalidLobbyInfo    ErrorCode = "INVALID_LOBBY_INFO"
+	ErrorCodeInvalidSettings     ErrorCode = "INVALID_SETTINGS"
+	ErrorCodeBanned              ErrorCode = "BANNED"
+	ErrorCodePlayerNotFound      ErrorCode = "PLAYER_NOT_FOUND"
+	ErrorCodeGameInProgress      ErrorCode = "GAME_IN_PROGRESS"
+	ErrorCodeNotEnoughPlayers    ErrorCode = "NOT_ENOUGH_PLAYERS"
+	ErrorCodePlayersNotReady     ErrorCode = "PLAYERS_NOT_READY"
+	ErrorCodeSpectatorAction     ErrorCode = "SPECTATOR_ACTION"
+	ErrorCodeSpectatorsDisabled  ErrorCode = "SPECTATORS_DISABLED"
+	ErrorCodeNotQueued           ErrorCode = "NOT_QUEUED"
+	ErrorCodeInvalidInviteToken  ErrorCode = "INVALID_INVITE_TOKEN"
+	ErrorCodeInvitationNotFound  ErrorCode = "INVITATION_NOT_FOUND"
+	ErrorCodeInvalidResumeToken  ErrorCode = "INVALID_RESUME_TOKEN"
+	ErrorCodeSeatNotReserved     ErrorCode = "SEAT_NOT_RESERVED"
+	ErrorCodeAmbiguousNickname   ErrorCode = "AMBIGUOUS_NICKNAME"
+	ErrorCodePlayerNotInvitable  ErrorCode = "PLAYER_NOT_INVITABLE"
+	ErrorCodePlayerDisconnected  ErrorCode = "PLAYER_DISCONNECTED"
+)
+
+// ProtocolError - ошибка с кодом, которую можно отдать клиенту как есть
+type ProtocolError struct {
+	Code    ErrorCode
+	Message string
+}
+
+func (e *ProtocolError) Error() string {
+	return e.Message
+}
+
+func protocolError(code ErrorCode, format string, args ...any) *ProtocolError {
+	return &ProtocolError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// errorCode достает код из ошибки, ошибки без кода считаются внутренними
+func errorCode(err error) ErrorCode {
+	var protocolErr *ProtocolError
+	if errors.As(err, &protocolErr) {
+		return protocolErr.Code
+	}
+	return ErrorCodeInternal
+}
+
+// ErrorResponse - конверт ошибки: код, тип и id запроса, который к ней привел
+// (пустые, если ошибка не ответ на запрос), человекочитаемое сообщение и его
+// перевод для пользователя, см. i18n.go
+type ErrorResponse struct {
+	Seq              uint64        `json:"seq,omitempty"` // проставляет writer
+	Type             WsMessageType `json:"type"`
+	Code             ErrorCode     `json:"code"`
+	RequestType      WsMessageType `json:"requestType,omitempty"`
+	RequestID        string        `json:"requestId,omitempty"`
+	Message          string        `json:"message,omitempty"`
+	LocalizedMessage string        `json:"localizedMessage,omitempty"`
+}
+
+func errorResponse(request inboundRequest, code ErrorCode, message, localized string) []byte {
+	response := ErrorResponse{
+		Type:             WsMessageTypeError,
+		Code:             code,
+		RequestType:      request.Type,
+		RequestID:        request.ID,
+		Message:          message,
+		LocalizedMessage: localized,
+	}
+
+	bytes, _ := json.Marshal(response)
+	return bytes
+}
+
+// sendError отвечает ошибкой на запрос, который сейчас обрабатывает читающая
+// горутина игрока, поэтому вызывается только из обработчиков его сообщений
+func (p *Player) sendError(code ErrorCode, message string) {
+	p.request.failed = true
+	p.send(p.errorMsg(p.request, code, message))
+}
+
+func (p *Player) sendErr(err error) {
+	p.sendError(errorCode(err), err.Error())
+}
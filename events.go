@@ -0,0 +1,58 @@
+package guesswho
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// сколько последних событий храним на лобби
+const maxLobbyEvents = 100
+
+type LobbyEventType string
+
+const (
+	LobbyEventCreated            LobbyEventType = "Created"
+	LobbyEventPlayerJoined       LobbyEventType = "PlayerJoined"
+	LobbyEventSpectatorJoined    LobbyEventType = "SpectatorJoined"
+	LobbyEventPlayerLeft         LobbyEventType = "PlayerLeft"
+	LobbyEventPlayerKicked       LobbyEventType = "PlayerKicked"
+	LobbyEventPlayerDisconnected LobbyEventType = "PlayerDisconnected"
+	LobbyEventPlayerTimedOut     LobbyEventType = "PlayerTimedOut"
+	LobbyEventPlayerReconnected  LobbyEventType = "PlayerReconnected"
+	LobbyEventHostChanged        LobbyEventType = "HostChanged"
+	LobbyEventSettingsChanged    LobbyEventType = "SettingsChanged"
+	LobbyEventLockChanged        LobbyEventType = "LockChanged"
+	LobbyEventGameStarted        LobbyEventType = "GameStarted"
+)
+
+type LobbyEvent struct {
+	Type     LobbyEventType `json:"type"`
+	PlayerID string         `json:"playerId,omitempty"`
+	Nickname string         `json:"nickname,omitempty"`
+	Details  string         `json:"details,omitempty"`
+	At       time.Time      `json:"at"`
+}
+
+// logEvent добавляет событие в журнал лобби, старые события вытесняются
+func (l *Lobby) logEvent(eventType LobbyEventType, player *Player, details string) {
+	event := LobbyEvent{
+		Type:    eventType,
+		Details: details,
+		At:      time.Now(),
+	}
+	if player != nil {
+		event.PlayerID = player.ID
+		event.Nickname = player.Nickname
+	}
+
+	l.events = append(l.events, event)
+	if len(l.events) > maxLobbyEvents {
+		l.events = l.events[len(l.events)-maxLobbyEvents:]
+	}
+}
+
+func handleGetLobbyEvents(player *Player, _ json.RawMessage) {
+	player.withLobby(func(lobby *Lobby) {
+		player.send(generateMsg(WsMessageTypeLobbyEvents, Payload{Lobby: &Lobby{ID: lobby.ID}, Events: lobby.events}))
+	})
+}
@@ -0,0 +1,71 @@
+package guesswho
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+)
+
+// transferHost делает игрока newHost хостом лобби
+func (l *Lobby) transferHost(newHost *Player) {
+	for _, player := range l.Players {
+		player.IsHost = player == newHost
+	}
+
+	log.Printf("INFO: player %s is now host of lobby %s", newHost.ID, l.ID)
+	l.logEvent(LobbyEventHostChanged, newHost, "")
+
+	l.broadcast(generateMsg(WsMessageTypeHostChanged, Payload{Lobby: l, Player: newHost}))
+}
+
+// reassignHost назначает хостом первого оставшегося игрока с живым соединением,
+// если хоста нет. Если живых нет, лобби ждет без хоста, пока кто-то не войдет
+// или не вернется, см. broadcastSnapshot
+func (l *Lobby) reassignHost() {
+	var newHost *Player
+	for _, player := range l.Players {
+		if player.IsHost {
+			return
+		}
+		if newHost == nil && !isDisconnected(player) {
+			newHost = player
+		}
+	}
+
+	if newHost != nil {
+		l.transferHost(newHost)
+	}
+}
+
+func handleTransferHost(player *Player, payloadJson json.RawMessage) {
+	var request TransferHostRequest
+	if !decodeRequest(player, payloadJson, &request) {
+		return
+	}
+
+	player.withLobby(func(lobby *Lobby) {
+		if !player.IsHost {
+			player.sendError(ErrorCodeNotHost, "ERROR: only the host can transfer host")
+			return
+		}
+
+		if request.Player.ID == player.ID {
+			player.sendError(ErrorCodeInvalidRequest, "ERROR: invalid player to transfer host to")
+			return
+		}
+
+		newHost := lobby.findPlayer(request.Player.ID)
+		if newHost == nil || newHost.IsSpectator {
+			player.sendError(ErrorCodePlayerNotFound, fmt.Sprintf("ERROR: player with id %s is not a player in the lobby", request.Player.ID))
+			return
+		}
+
+		// место без связи только удерживается, хостом оно быть не может
+		if isDisconnected(newHost) {
+			player.sendError(ErrorCodePlayerDisconnected, fmt.Sprintf("ERROR: player with id %s is not connected", request.Player.ID))
+			return
+		}
+
+		lobby.transferHost(newHost)
+	})
+}
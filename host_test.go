@@ -0,0 +1,47 @@
+package guesswho
+
+import (
+	"fmt"
+	"testing"
+)
+
+// хост не может передать роль месту, которое только удерживается
+func TestTransferHostToDisconnectedPlayer(t *testing.T) {
+	server := newTestServer(t, DefaultOptions())
+
+	host := server.dial(t, "")
+	host.createLobby("HST001", 4)
+
+	member := server.dial(t, "")
+	member.joinLobby("HST001", "member")
+	member.expect(WsMessageTypeLobbyJoined)
+	server.dropConnection(t, member, "HST001")
+
+	host.send(WsMessageTypeTransferHost, fmt.Sprintf(`{"player":{"id":%q}}`, member.id))
+	host.expectError(ErrorCodePlayerDisconnected)
+}
+
+// все игроки без связи, лобби без хоста: хостом становится первый вошедший
+func TestHostAssignedToJoinerOfHostlessLobby(t *testing.T) {
+	server := newTestServer(t, DefaultOptions())
+
+	host := server.dial(t, "")
+	host.createLobby("HST002", 4)
+
+	member := server.dial(t, "")
+	member.joinLobby("HST002", "member")
+	member.expect(WsMessageTypeLobbyJoined)
+
+	server.dropConnection(t, member, "HST002")
+	server.dropConnection(t, host, "HST002")
+
+	guest := server.dial(t, "")
+	guest.joinLobby("HST002", "guest")
+	guest.expect(WsMessageTypeLobbyJoined)
+	if changed := guest.expect(WsMessageTypeHostChanged).decode(t); changed.Player == nil || changed.Player.ID != guest.id {
+		t.Fatalf("got new host %+v, want %s", changed.Player, guest.id)
+	}
+
+	guest.send(WsMessageTypeLockLobby, "")
+	guest.expect(WsMessageTypeLobbyLockChanged)
+}
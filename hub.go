@@ -0,0 +1,88 @@
+package guesswho
+
+import "context"
+
+// Хаб лобби: состоянием лобби - составом, очередью, настройками, журналом,
+// дельтами и флагами участников (хост, готовность, зритель) - владеет одна
+// горутина run, запущенная при создании лобби. Остальные горутины (читающие
+// соединения, таймеры, janitor, matchmaker, HTTP обработчики) это состояние
+// не трогают, а присылают хабу события и ждут их выполнения в do, поэтому у
+// лобби нет мьютекса. События могут брать Server.mu, но Server.mu никогда не
+// держится во время do, так что взаимной блокировки лобби и сервера нет.
+//
+// Методы лобби без do (join, leave, broadcast, snapshot и т.д.) вызываются
+// только из событий, то есть уже в горутине хаба, и сами не должны вызывать
+// do - ни своего лобби, ни чужого: хаб ждал бы сам себя. По той же причине
+// события не вызывают disconnect, его onDisconnect сам идет в хаб. Внутри
+// события можно брать Server.mu, invitations.mu, invites.mu и Player.mu, но не
+// наоборот.
+//
+// Сериализовать игрока целиком тоже можно только в хабе его лобби: флаги -
+// часть состояния лобби. Сообщения, которые собираются вне хаба (приглашения,
+// SyncState без лобби, логи других горутин), берут копию Player.profile или
+// Player.id
+
+// run выполняет события лобби по одному, пока лобби не закрыто или не
+// остановлен сервер
+func (l *Lobby) run(ctx context.Context) {
+	defer close(l.closed)
+
+	for !l.stopped {
+		select {
+		case event := <-l.inbox:
+			event()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// do выполняет event в горутине хаба и ждет его завершения, false - лобби уже
+// закрыто и event не выполнялся
+func (l *Lobby) do(event func()) bool {
+	done := make(chan struct{})
+	select {
+	case l.inbox <- func() {
+		defer close(done)
+		event()
+	}:
+	case <-l.closed:
+		return false
+	}
+
+	<-done
+	return true
+}
+
+// withLobby выполняет event в хабе лобби игрока. Если игрок не в лобби или
+// успел из него выйти, пока событие ждало очереди, отправляет ему NOT_IN_LOBBY.
+// Лобби сверх своего лимита трафика сначала придерживает игрока, см. throttleLobby
+func (p *Player) withLobby(event func(lobby *Lobby)) {
+	inLobby := false
+	if lobby := p.lobby(); lobby != nil {
+		throttleLobby(p, lobby)
+		lobby.do(func() {
+			if inLobby = p.lobby() == lobby; inLobby {
+				event(lobby)
+			}
+		})
+	}
+
+	if !inLobby {
+		p.sendError(ErrorCodeNotInLobby, "ERROR: player is not in a lobby")
+	}
+}
+
+// withLobby выполняет event в хабе лобби lobbyID и возвращает его ошибку,
+// LOBBY_NOT_FOUND - такого лобби нет или оно уже закрыто
+func (s *Server) withLobby(lobbyID string, event func(lobby *Lobby) error) error {
+	s.mu.Lock()
+	lobby, exists := s.Lobbies[lobbyID]
+	s.mu.Unlock()
+
+	var err error
+	if !exists || !lobby.do(func() { err = event(lobby) }) {
+		return protocolError(ErrorCodeLobbyNotFound, "ERROR: lobby with id %s not found", lobbyID)
+	}
+	return err
+}
@@ -0,0 +1,224 @@
+package guesswho
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+// запускать с -race: состояние лобби без мьютекса трогают только события хаба
+
+// do из многих горутин выполняет события по одному, после закрытия лобби -
+// больше ни одного
+func TestHubRunsEventsOneByOne(t *testing.T) {
+	server := newTestServer(t, DefaultOptions())
+
+	host, _ := server.newPlayer(context.Background(), nil, url.Values{})
+	lobby, err := server.createLobby(host, LobbyOptions{Code: "HUB001"})
+	if err != nil {
+		t.Fatalf("createLobby: %v", err)
+	}
+
+	const events = 200
+	count := 0
+	var wg sync.WaitGroup
+	for range events {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !lobby.do(func() { count++ }) {
+				t.Error("do refused an event of an open lobby")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if count != events {
+		t.Fatalf("got %d events, want %d", count, events)
+	}
+
+	lobby.do(func() { lobby.close(LobbyCloseReasonEmpty) })
+	if lobby.do(func() { count++ }) {
+		t.Error("do ran an event of a closed lobby")
+	}
+}
+
+// waitClosed ждет, пока сервер закроет соединение
+func (c *testClient) waitClosed() {
+	c.t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-c.messages:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			c.t.Fatal("timed out waiting for the connection to close")
+		}
+	}
+}
+
+// игроки одновременно входят, жмут готовность и уходят, пока хост меняет
+// настройки: в конце в лобби только хост и оставшиеся игроки
+func TestHubConcurrentLobbyTraffic(t *testing.T) {
+	server := newTestServer(t, DefaultOptions())
+
+	host := server.dial(t, "")
+	host.createLobby("HUB002", 8)
+
+	const players = 6
+	clients := make([]*testClient, players)
+	for i := range clients {
+		clients[i] = server.dial(t, "")
+	}
+
+	// горутины только пишут в свои соединения, ответы читает тест
+	var wg sync.WaitGroup
+	for i, client := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			client.joinLobby("HUB002", fmt.Sprintf("player%d", i))
+			for range 5 {
+				client.send(WsMessageTypePlayerReady, "")
+				client.send(WsMessageTypePlayerUnready, "")
+			}
+			if i%2 == 0 {
+				client.send(WsMessageTypePlayerQuit, "")
+			} else {
+				client.send(WsMessageTypeGetLobbyEvents, "")
+			}
+		}()
+	}
+	for i := range 10 {
+		host.send(WsMessageTypeUpdateLobbySettings, fmt.Sprintf(`{"settings":{"turnTimerSeconds":%d,"gameMode":"Classic","characterPack":"default","maxPlayers":8}}`, 30+i))
+	}
+	wg.Wait()
+
+	want := map[string]bool{host.id: true}
+	for i, client := range clients {
+		client.expect(WsMessageTypeLobbyJoined)
+		if i%2 == 0 {
+			client.waitClosed()
+			continue
+		}
+		// ответ на последний запрос - остальные уже выполнены
+		client.expect(WsMessageTypeLobbyEvents)
+		want[client.id] = true
+	}
+
+	server.mu.Lock()
+	lobby := server.Lobbies["HUB002"]
+	server.mu.Unlock()
+
+	lobby.do(func() {
+		if len(lobby.Players) != len(want) {
+			t.Errorf("got %d players, want %d", len(lobby.Players), len(want))
+		}
+		for _, player := range lobby.Players {
+			if !want[player.ID] || player.lobby() != lobby {
+				t.Errorf("unexpected player %s in the lobby", player.ID)
+			}
+		}
+	})
+}
+
+// новые соединения забирают сессии живых игроков, пока лобби шлет сообщения,
+// а пинги уже читают ID новых соединений
+func TestHubResumeLiveSession(t *testing.T) {
+	opts := DefaultOptions()
+	opts.ConnectionQualityInterval = 5 * time.Millisecond
+	server := newTestServer(t, opts)
+
+	host := server.dial(t, "")
+	host.createLobby("HUB003", 4)
+
+	member := server.dial(t, "")
+	member.joinLobby("HUB003", "member")
+	member.expect(WsMessageTypeLobbyJoined)
+
+	lonely := server.dial(t, "")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 20 {
+			host.send(WsMessageTypePlayerReady, "")
+			host.send(WsMessageTypePlayerUnready, "")
+		}
+	}()
+
+	// игрок в лобби возвращается через RejoinLobby, без лобби - при подключении
+	resumed := server.dial(t, "")
+	moved := server.dial(t, "resumeToken="+url.QueryEscape(lonely.resumeToken))
+	time.Sleep(20 * time.Millisecond)
+	resumed.send(WsMessageTypeRejoinLobby, fmt.Sprintf(`{"resumeToken":%q}`, member.resumeToken))
+	resumed.expect(WsMessageTypePlayerReconnected)
+	<-done
+
+	if moved.id != lonely.id {
+		t.Fatalf("got id %s, want %s", moved.id, lonely.id)
+	}
+	member.waitClosed()
+	lonely.waitClosed()
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	for _, session := range []struct{ id, resumeToken string }{{member.id, member.resumeToken}, {moved.id, moved.resumeToken}} {
+		player := server.Players[session.id]
+		if player == nil || server.Sessions[session.resumeToken] != player {
+			t.Errorf("session of player %s is not bound to the new connection", session.id)
+		}
+	}
+}
+
+// приглашения и SyncState собираются вне хабов, пока хабы меняют флаги тех
+// же игроков
+func TestHubInvitationsAndSyncDuringHostChanges(t *testing.T) {
+	server := newTestServer(t, DefaultOptions())
+
+	host := server.dial(t, "")
+	host.createLobby("HUB004", 4)
+
+	member := server.dial(t, "")
+	member.joinLobby("HUB004", "member")
+	member.expect(WsMessageTypeLobbyJoined)
+
+	outsider := server.dial(t, "")
+
+	const rounds = 10
+	var wg sync.WaitGroup
+	for _, c := range []struct{ client, other *testClient }{{host, member}, {member, host}} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range rounds {
+				c.client.send(WsMessageTypeTransferHost, fmt.Sprintf(`{"player":{"id":%q}}`, c.other.id))
+				c.client.send(WsMessageTypePlayerReady, "")
+				c.client.send(WsMessageTypeInvitePlayer, fmt.Sprintf(`{"player":{"id":%q}}`, outsider.id))
+			}
+			c.client.send(WsMessageTypeRequestSync, "")
+			c.client.send(WsMessageTypeGetLobbyEvents, "")
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range rounds {
+			outsider.send(WsMessageTypeRequestSync, "")
+		}
+	}()
+	wg.Wait()
+
+	host.expect(WsMessageTypeLobbyEvents)
+	member.expect(WsMessageTypeLobbyEvents)
+	for range rounds {
+		outsider.expect(WsMessageTypeSyncState)
+	}
+}
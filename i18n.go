@@ -0,0 +1,109 @@
+package guesswho
+
+import "strings"
+
+// Переводы сообщений для пользователя. Клиент объявляет локаль при подключении
+// (query параметр locale), и в конверт ошибки кроме message, который остается
+// английским и подробным для логов и отладки, добавляется localizedMessage -
+// короткий текст на языке клиента, который можно показать в UI
+
+const defaultLocale = "en"
+
+var errorMessages = map[string]map[ErrorCode]string{
+	"en": {
+		ErrorCodeInternal:            "Something went wrong on the server. Please try again.",
+		ErrorCodeInvalidRequest:      "The request is invalid.",
+		ErrorCodeUnknownMessageType:  "The server doesn't understand this request.",
+		ErrorCodeUnsupportedProtocol: "This version of the game is not supported. Please update.",
+		ErrorCodeRateLimited:         "You are doing that too often. Please slow down.",
+		ErrorCodeMessageTooLarge:     "The message is too large.",
+		ErrorCodeInvalidNickname:     "This nickname can't be used.",
+		ErrorCodeInvalidAvatar:       "This avatar doesn't exist.",
+		ErrorCodeServerAtCapacity:    "The server is full. Please try again later.",
+		ErrorCodeNicknameReserved:    "This nickname is reserved.",
+		ErrorCodeAlreadyInLobby:      "You are already in a lobby.",
+		ErrorCodeNotInLobby:          "You are not in a lobby.",
+		ErrorCodeNotHost:             "Only the host can do that.",
+		ErrorCodeLobbyNotFound:       "Lobby not found.",
+		ErrorCodeLobbyFull:           "The lobby is full.",
+		ErrorCodeLobbyLocked:         "The lobby is locked.",
+		ErrorCodeLobbyCodeTaken:      "This lobby code is already taken.",
+		ErrorCodeInvalidLobbyCode:    "The lobby code is invalid.",
+		ErrorCodeInvalidLobbyInfo:    "The lobby name or region is invalid.",
+		ErrorCodeInvalidSettings:     "The lobby settings are invalid.",
+		ErrorCodeBanned:              "You are banned from this lobby.",
+		ErrorCodePlayerNotFound:      "Player not found.",
+		ErrorCodeGameInProgress:      "The game has already started.",
+		ErrorCodeNotEnoughPlayers:    "Not enough players to start.",
+		ErrorCodePlayersNotReady:     "Not all players are ready.",
+		ErrorCodeSpectatorAction:     "Spectators can't do that.",
+		ErrorCodeSpectatorsDisabled:  "Spectators are not allowed in this lobby.",
+		ErrorCodeNotQueued:           "You are not in the queue for this lobby.",
+		ErrorCodeInvalidInviteToken:  "The invite link is invalid or has expired.",
+		ErrorCodeInvitationNotFound:  "The invitation has expired.",
+		ErrorCodeInvalidResumeToken:  "The session has expired.",
+		ErrorCodeSeatNotReserved:     "Your seat in the lobby is no longer reserved.",
+		ErrorCodeAmbiguousNickname:   "Several players have this nickname.",
+		ErrorCodePlayerNotInvitable:  "This player can't be invited.",
+		ErrorCodePlayerDisconnected:  "This player is not connected right now.",
+	},
+	"ru": {
+		ErrorCodeInternal:            "На сервере что-то пошло не так. Попробуйте еще раз.",
+		ErrorCodeInvalidRequest:      "Некорректный запрос.",
+		ErrorCodeUnknownMessageType:  "Сервер не понимает этот запрос.",
+		ErrorCodeUnsupportedProtocol: "Эта версия игры не поддерживается. Обновите игру.",
+		ErrorCodeRateLimited:         "Слишком часто. Подождите немного.",
+		ErrorCodeMessageTooLarge:     "Сообщение слишком большое.",
+		ErrorCodeInvalidNickname:     "Этот ник нельзя использовать.",
+		ErrorCodeInvalidAvatar:       "Такого аватара нет.",
+		ErrorCodeServerAtCapacity:    "Сервер переполнен. Попробуйте позже.",
+		ErrorCodeNicknameReserved:    "Этот ник зарезервирован.",
+		ErrorCodeAlreadyInLobby:      "Вы уже в лобби.",
+		ErrorCodeNotInLobby:          "Вы не в лобби.",
+		ErrorCodeNotHost:             "Это может сделать только хост.",
+		ErrorCodeLobbyNotFound:       "Лобби не найдено.",
+		ErrorCodeLobbyFull:           "Лобби заполнено.",
+		ErrorCodeLobbyLocked:         "Лобби закрыто.",
+		ErrorCodeLobbyCodeTaken:      "Этот код лобби уже занят.",
+		ErrorCodeInvalidLobbyCode:    "Неверный код лобби.",
+		ErrorCodeInvalidLobbyInfo:    "Неверное название или регион лобби.",
+		ErrorCodeInvalidSettings:     "Неверные настройки лобби.",
+		ErrorCodeBanned:              "Вас заблокировали в этом лобби.",
+		ErrorCodePlayerNotFound:      "Игрок не найден.",
+		ErrorCodeGameInProgress:      "Игра уже началась.",
+		ErrorCodeNotEnoughPlayers:    "Недостаточно игроков для старта.",
+		ErrorCodePlayersNotReady:     "Не все игроки готовы.",
+		ErrorCodeSpectatorAction:     "Зрителям это недоступно.",
+		ErrorCodeSpectatorsDisabled:  "В этом лобби нельзя смотреть игру.",
+		ErrorCodeNotQueued:           "Вы не в очереди в это лобби.",
+		ErrorCodeInvalidInviteToken:  "Ссылка-приглашение неверна или устарела.",
+		ErrorCodeInvitationNotFound:  "Приглашение устарело.",
+		ErrorCodeInvalidResumeToken:  "Сессия устарела.",
+		ErrorCodeSeatNotReserved:     "Ваше место в лобби больше не держится.",
+		ErrorCodeAmbiguousNickname:   "Этот ник у нескольких игроков.",
+		ErrorCodePlayerNotInvitable:  "Этого игрока нельзя пригласить.",
+		ErrorCodePlayerDisconnected:  "Этот игрок сейчас не в сети.",
+	},
+}
+
+// parseLocale выбирает локаль из объявленной клиентом: "ru-RU" и "ru_RU"
+// считаются "ru", неизвестные локали - defaultLocale
+func parseLocale(raw string) string {
+	locale := strings.ToLower(raw)
+	if i := strings.IndexAny(locale, "-_"); i >= 0 {
+		locale = locale[:i]
+	}
+
+	if _, exists := errorMessages[locale]; !exists {
+		return defaultLocale
+	}
+	return locale
+}
+
+// localizeError возвращает текст ошибки для пользователя на языке locale
+func localizeError(locale string, code ErrorCode) string {
+	if message, exists := errorMessages[locale][code]; exists {
+		return message
+	}
+	return errorMessages[defaultLocale][code]
+}
@@ -0,0 +1,103 @@
+package guesswho
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Ключи идемпотентности: клиент, который не дождался ответа на CreateLobby или
+// JoinLobby и повторяет запрос, передает в конверте тот же idempotencyKey.
+// Сервер помнит ответ на успешный запрос и на повтор отвечает им же, а не
+// создает второе лобби или ошибку ALREADY_IN_LOBBY. Ключи живут в пределах
+// сессии игрока, поэтому переживают возвращение по resume token. Неуспешные
+// запросы не запоминаются: они ничего не изменили, и повтор выполняется заново
+
+const maxIdempotencyKeyLength = 128
+
+// idempotentMessages - запросы, для которых принимается idempotencyKey
+var idempotentMessages = map[WsMessageType]struct{}{
+	WsMessageTypeCreateLobby: {},
+	WsMessageTypeJoinLobby:   {},
+}
+
+type idempotentResult struct {
+	requestType WsMessageType
+	response    []byte // ответ, который получил игрок, уже без seq
+	expiresAt   time.Time
+}
+
+// запомненные ответы сервера, живут Options.IdempotencyKeyTTL
+type idempotencyStore struct {
+	results map[string]idempotentResult // по ID игрока и ключу
+	mu      sync.Mutex
+}
+
+func idempotencyScope(player *Player, key string) string {
+	return player.ID + "/" + key
+}
+
+// replayIdempotent отвечает на повтор запроса с уже известным ключом, false -
+// ключ новый и запрос нужно выполнить
+func replayIdempotent(player *Player, msg WsMessage) bool {
+	if msg.IdempotencyKey == "" {
+		return false
+	}
+
+	if _, supported := idempotentMessages[msg.Type]; !supported {
+		player.sendError(ErrorCodeInvalidRequest, "ERROR: idempotencyKey is not supported for "+string(msg.Type))
+		return true
+	}
+	if len(msg.IdempotencyKey) > maxIdempotencyKeyLength {
+		player.sendError(ErrorCodeInvalidRequest, "ERROR: idempotencyKey is too long")
+		return true
+	}
+
+	idempotency := &player.server.idempotency
+	idempotency.mu.Lock()
+	result, exists := idempotency.results[idempotencyScope(player, msg.IdempotencyKey)]
+	idempotency.mu.Unlock()
+
+	if !exists || time.Now().After(result.expiresAt) {
+		return false
+	}
+
+	if result.requestType != msg.Type {
+		player.sendError(ErrorCodeInvalidRequest, "ERROR: idempotencyKey was already used for "+string(result.requestType))
+		return true
+	}
+
+	log.Printf("INFO: replaying %s response to player %s for idempotency key %s", msg.Type, player.ID, msg.IdempotencyKey)
+	player.send(result.response)
+	return true
+}
+
+// rememberIdempotent запоминает ответ на успешно выполненный запрос с ключом
+func rememberIdempotent(player *Player, msg WsMessage) {
+	request := player.request
+	if msg.IdempotencyKey == "" || request.failed || request.response == nil {
+		return
+	}
+
+	idempotency := &player.server.idempotency
+	idempotency.mu.Lock()
+	idempotency.results[idempotencyScope(player, msg.IdempotencyKey)] = idempotentResult{
+		requestType: msg.Type,
+		response:    request.response,
+		expiresAt:   time.Now().Add(player.server.opts.IdempotencyKeyTTL),
+	}
+	idempotency.mu.Unlock()
+}
+
+// purgeExpiredIdempotencyKeys вызывается janitor'ом
+func (s *Server) purgeExpiredIdempotencyKeys() {
+	s.idempotency.mu.Lock()
+	defer s.idempotency.mu.Unlock()
+
+	now := time.Now()
+	for scope, result := range s.idempotency.results {
+		if now.After(result.expiresAt) {
+			delete(s.idempotency.results, scope)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package guesswho
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type InvitationStatus string
+
+const (
+	InvitationStatusPending  InvitationStatus = "Pending"
+	InvitationStatusAccepted InvitationStatus = "Accepted"
+	InvitationStatusDeclined InvitationStatus = "Declined"
+	InvitationStatusExpired  InvitationStatus = "Expired"
+)
+
+// Invitation сериализуется без хаба лобби, поэтому From и To - копии профилей
+// на момент приглашения (см. Player.profile), а сами соединения в from и to
+type Invitation struct {
+	ID        string           `json:"id"`
+	LobbyID   string           `json:"lobbyId"`
+	From      *Player          `json:"from"`
+	To        *Player          `json:"to"`
+	Status    InvitationStatus `json:"status"`
+	ExpiresAt time.Time        `json:"expiresAt"`
+
+	from *Player
+	to   *Player
+}
+
+// приглашения онлайн игроков в лобби, живут Options.PlayerInvitationTTL
+type invitationStore struct {
+	byID map[string]*Invitation
+	mu   sync.Mutex
+}
+
+// findOnlinePlayer ищет подключенного игрока по ID или нику
+func (s *Server) findOnlinePlayer(id, nickname string) (*Player, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id != "" {
+		if player, exists := s.Players[id]; exists && !isDisconnected(player) {
+			return player, nil
+		}
+		return nil, protocolError(ErrorCodePlayerNotFound, "ERROR: player with id %s is not online", id)
+	}
+
+	var found *Player
+	for _, player := range s.Players {
+		if isDisconnected(player) || !strings.EqualFold(player.nickname(), nickname) {
+			continue
+		}
+		if found != nil {
+			return nil, protocolError(ErrorCodeAmbiguousNickname, "ERROR: several online players have nickname %s, invite by id", nickname)
+		}
+		found = player
+	}
+
+	if found == nil {
+		return nil, protocolError(ErrorCodePlayerNotFound, "ERROR: player with nickname %s is not online", nickname)
+	}
+	return found, nil
+}
+
+// resolveInvitation переводит приглашение из Pending в status и сообщает обоим
+func (s *Server) resolveInvitation(invitation *Invitation, status InvitationStatus) bool {
+	s.invitations.mu.Lock()
+	if invitation.Status != InvitationStatusPending {
+		s.invitations.mu.Unlock()
+		return false
+	}
+	invitation.Status = status
+	delete(s.invitations.byID, invitation.ID)
+	msg := generateMsg(WsMessageTypeInvitationUpdated, Payload{Invitation: invitation})
+	s.invitations.mu.Unlock()
+
+	log.Printf("INFO: invitation %s is now %s", invitation.ID, status)
+
+	for _, player := range []*Player{invitation.from, invitation.to} {
+		if !isDisconnected(player) {
+			player.send(msg)
+		}
+	}
+	return true
+}
+
+func handleInvitePlayer(player *Player, payloadJson json.RawMessage) {
+	var request InvitePlayerRequest
+	if !decodeRequest(player, payloadJson, &request) {
+		return
+	}
+
+	var lobbyID string
+	if lobby := player.lobby(); lobby != nil {
+		lobby.do(func() {
+			if player.lobby() == lobby && !player.IsSpectator {
+				lobbyID = lobby.ID
+			}
+		})
+	}
+	if lobbyID == "" {
+		player.sendError(ErrorCodeNotInLobby, "ERROR: player is not in a lobby")
+		return
+	}
+
+	s := player.server
+	invitee, err := s.findOnlinePlayer(request.Player.ID, request.Player.Nickname)
+	if err != nil {
+		player.sendErr(err)
+		return
+	}
+
+	if invitee == player || invitee.lobby() != nil {
+		player.sendError(ErrorCodePlayerNotInvitable, fmt.Sprintf("ERROR: player %s can't be invited", invitee.id()))
+		return
+	}
+
+	invitation := &Invitation{
+		ID:        uuid.New().String(),
+		LobbyID:   lobbyID,
+		From:      player.profile(),
+		To:        invitee.profile(),
+		Status:    InvitationStatusPending,
+		ExpiresAt: time.Now().Add(s.opts.PlayerInvitationTTL),
+		from:      player,
+		to:        invitee,
+	}
+
+	s.invitations.mu.Lock()
+	s.invitations.byID[invitation.ID] = invitation
+	msg := generateMsg(WsMessageTypeInvitationReceived, Payload{Invitation: invitation})
+	s.invitations.mu.Unlock()
+
+	time.AfterFunc(s.opts.PlayerInvitationTTL, func() {
+		s.resolveInvitation(invitation, InvitationStatusExpired)
+	})
+
+	log.Printf("INFO: player %s invited player %s to lobby %s", player.ID, invitation.To.ID, lobbyID)
+
+	invitee.send(msg)
+	player.send(generateMsg(WsMessageTypeInvitationUpdated, Payload{Invitation: invitation}))
+}
+
+func handleRespondToInvitation(player *Player, payloadJson json.RawMessage, accept bool) {
+	var request RespondToInvitationRequest
+	if !decodeRequest(player, payloadJson, &request) {
+		return
+	}
+
+	s := player.server
+	s.invitations.mu.Lock()
+	invitation, exists := s.invitations.byID[request.Invitation.ID]
+	s.invitations.mu.Unlock()
+
+	if !exists || invitation.to != player {
+		player.sendError(ErrorCodeInvitationNotFound, "ERROR: invitation not found or expired")
+		return
+	}
+
+	if !accept {
+		s.resolveInvitation(invitation, InvitationStatusDeclined)
+		return
+	}
+
+	if player.lobby() != nil {
+		player.sendError(ErrorCodeAlreadyInLobby, "ERROR: player is already in a lobby")
+		return
+	}
+
+	err := s.withLobby(invitation.LobbyID, func(lobby *Lobby) error {
+		if err := lobby.join(player, false); err != nil {
+			return err
+		}
+
+		s.resolveInvitation(invitation, InvitationStatusAccepted)
+
+		lobby.broadcastSnapshot(WsMessageTypeLobbyJoined, player)
+		lobby.maybeAutoStart()
+		return nil
+	})
+	if err != nil {
+		player.sendErr(err)
+	}
+}
@@ -0,0 +1,34 @@
+package guesswho
+
+import (
+	"fmt"
+	"testing"
+)
+
+// приглашение несет профили обоих игроков, по нему можно войти в лобби
+func TestInvitePlayer(t *testing.T) {
+	server := newTestServer(t, DefaultOptions())
+
+	host := server.dial(t, "")
+	host.createLobby("IVT001", 4)
+
+	guest := server.dial(t, "")
+
+	host.send(WsMessageTypeInvitePlayer, fmt.Sprintf(`{"player":{"id":%q}}`, guest.id))
+	invitation := guest.expect(WsMessageTypeInvitationReceived).decode(t).Invitation
+	if invitation == nil || invitation.From == nil || invitation.To == nil {
+		t.Fatalf("got invitation %+v", invitation)
+	}
+	if invitation.From.ID != host.id || invitation.From.Nickname != "host" || invitation.To.ID != guest.id {
+		t.Errorf("got invitation from %+v to %+v", invitation.From, invitation.To)
+	}
+
+	guest.send(WsMessageTypeAcceptInvitation, fmt.Sprintf(`{"invitation":{"id":%q}}`, invitation.ID))
+	guest.expect(WsMessageTypeLobbyJoined)
+	if updated := host.expect(WsMessageTypeInvitationUpdated).decode(t).Invitation; updated == nil || updated.Status != InvitationStatusPending {
+		t.Errorf("got invitation %+v, want the pending one first", updated)
+	}
+	if updated := host.expect(WsMessageTypeInvitationUpdated).decode(t).Invitation; updated == nil || updated.Status != InvitationStatusAccepted {
+		t.Errorf("got invitation %+v, want accepted", updated)
+	}
+}
@@ -0,0 +1,165 @@
+package guesswho
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+type invite struct {
+	lobbyID   string
+	expiresAt time.Time
+}
+
+// одноразовые приглашения в лобби, живут Options.InviteTokenTTL
+type inviteStore struct {
+	tokens map[string]invite
+	mu     sync.Mutex
+}
+
+func (s *Server) mintInvite(lobbyID string) (string, time.Time, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", time.Time{}, err
+	}
+	token := hex.EncodeToString(raw)
+	expiresAt := time.Now().Add(s.opts.InviteTokenTTL)
+
+	s.invites.mu.Lock()
+	s.invites.tokens[token] = invite{lobbyID: lobbyID, expiresAt: expiresAt}
+	s.invites.mu.Unlock()
+
+	return token, expiresAt, nil
+}
+
+// checkInvite проверяет приглашение в лобби lobbyID, не гася его. Проверка и
+// consumeInvite идут в одном событии хаба лобби, поэтому одно приглашение не
+// пустит двоих
+func (s *Server) checkInvite(token, lobbyID string) error {
+	s.invites.mu.Lock()
+	defer s.invites.mu.Unlock()
+
+	inv, exists := s.invites.tokens[token]
+	if !exists || time.Now().After(inv.expiresAt) {
+		delete(s.invites.tokens, token)
+		return protocolError(ErrorCodeInvalidInviteToken, "ERROR: invite token is invalid or expired")
+	}
+
+	if inv.lobbyID != lobbyID {
+		return protocolError(ErrorCodeInvalidInviteToken, "ERROR: invite token is not valid for lobby %s", lobbyID)
+	}
+	return nil
+}
+
+// consumeInvite гасит приглашение, когда игрок по нему уже вошел
+func (s *Server) consumeInvite(token string) {
+	s.invites.mu.Lock()
+	delete(s.invites.tokens, token)
+	s.invites.mu.Unlock()
+}
+
+func (s *Server) purgeExpiredInvites() {
+	s.invites.mu.Lock()
+	defer s.invites.mu.Unlock()
+
+	for token, inv := range s.invites.tokens {
+		if time.Now().After(inv.expiresAt) {
+			delete(s.invites.tokens, token)
+		}
+	}
+}
+
+// sessionPlayer находит игрока по resume token из заголовка
+// Authorization: Bearer <resumeToken>, nil - заголовка нет или сессия неизвестна
+func (s *Server) sessionPlayer(r *http.Request) *Player {
+	resumeToken, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || resumeToken == "" {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Sessions[resumeToken]
+}
+
+// handleInvite выдает приглашение в лобби только его хосту: хост подтверждает
+// себя resume token своей сессии. CORS открыт лишь для Origin, которые
+// пропускает Options.CheckOrigin
+func (s *Server) handleInvite(w http.ResponseWriter, r *http.Request) {
+	if origin := r.Header.Get("Origin"); origin != "" && s.opts.CheckOrigin != nil && s.opts.CheckOrigin(r) {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization")
+		w.Header().Set("Access-Control-Allow-Methods", "POST")
+		w.Header().Add("Vary", "Origin")
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte(`{"error": "Метод не поддерживается"}`))
+		return
+	}
+
+	player := s.sessionPlayer(r)
+	if player == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "Требуется авторизация"}`))
+		return
+	}
+
+	lobbyID := r.PathValue("id")
+
+	// хоста проверяем в хабе, чтобы он не сменился между проверкой и выдачей
+	var token string
+	var expiresAt time.Time
+	err := s.withLobby(lobbyID, func(lobby *Lobby) error {
+		if player.lobby() != lobby || !player.IsHost {
+			return protocolError(ErrorCodeNotHost, "ERROR: only the host can invite to lobby %s", lobbyID)
+		}
+
+		var err error
+		token, expiresAt, err = s.mintInvite(lobbyID)
+		return err
+	})
+
+	var protocolErr *ProtocolError
+	switch {
+	case err == nil:
+	case errors.As(err, &protocolErr) && protocolErr.Code == ErrorCodeLobbyNotFound:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "Лобби не найдено"}`))
+		return
+	case errors.As(err, &protocolErr) && protocolErr.Code == ErrorCodeNotHost:
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"error": "Приглашать может только хост"}`))
+		return
+	default:
+		log.Printf("ERROR: can't mint invite token for lobby %s, error: %v", lobbyID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Не удалось создать приглашение"}`))
+		return
+	}
+
+	response := struct {
+		LobbyID   string    `json:"lobbyId"`
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expiresAt"`
+	}{
+		LobbyID:   lobbyID,
+		Token:     token,
+		ExpiresAt: expiresAt,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
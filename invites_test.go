@@ -0,0 +1,160 @@
+package guesswho
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// joinWithInvite входит в лобби code по приглашению token, но ответ не ждет
+func (c *testClient) joinWithInvite(code, nickname, token string) {
+	c.t.Helper()
+
+	c.send(WsMessageTypeJoinLobby, fmt.Sprintf(`{"player":{"nickname":%q},"lobby":{"id":%q},"inviteToken":%q}`, nickname, code, token))
+}
+
+// приглашение пускает в закрытое лобби и гасится только после входа
+func TestInviteJoinsLockedLobby(t *testing.T) {
+	server := newTestServer(t, DefaultOptions())
+
+	host := server.dial(t, "")
+	host.createLobby("INV001", 2)
+	host.send(WsMessageTypeLockLobby, "")
+	host.expect(WsMessageTypeLobbyLockChanged)
+
+	token, _, err := server.mintInvite("INV001")
+	if err != nil {
+		t.Fatalf("mintInvite: %v", err)
+	}
+
+	stranger := server.dial(t, "")
+	stranger.joinLobby("INV001", "stranger")
+	stranger.expectError(ErrorCodeLobbyLocked)
+
+	guest := server.dial(t, "")
+	guest.joinWithInvite("INV001", "guest", token)
+	guest.expect(WsMessageTypeLobbyJoined)
+
+	guest.send(WsMessageTypePlayerQuit, "")
+	host.expect(WsMessageTypePlayerLeft)
+
+	again := server.dial(t, "")
+	again.joinWithInvite("INV001", "again", token)
+	again.expectError(ErrorCodeInvalidInviteToken)
+}
+
+// неудачный вход не сжигает приглашение
+func TestInviteSurvivesFailedJoin(t *testing.T) {
+	server := newTestServer(t, DefaultOptions())
+
+	host := server.dial(t, "")
+	host.createLobby("INV002", 2)
+
+	token, _, err := server.mintInvite("INV002")
+	if err != nil {
+		t.Fatalf("mintInvite: %v", err)
+	}
+
+	first := server.dial(t, "")
+	first.joinLobby("INV002", "first")
+	first.expect(WsMessageTypeLobbyJoined)
+
+	guest := server.dial(t, "")
+	guest.joinWithInvite("INV002", "guest", token)
+	guest.expectError(ErrorCodeLobbyFull)
+
+	first.send(WsMessageTypePlayerQuit, "")
+	host.expect(WsMessageTypePlayerLeft)
+
+	guest.joinWithInvite("INV002", "guest", token)
+	guest.expect(WsMessageTypeLobbyJoined)
+}
+
+// приглашение в одно лобби не открывает другое
+func TestInviteWrongLobby(t *testing.T) {
+	server := newTestServer(t, DefaultOptions())
+
+	server.dial(t, "").createLobby("INV003", 4)
+	server.dial(t, "").createLobby("INV004", 4)
+
+	token, _, err := server.mintInvite("INV003")
+	if err != nil {
+		t.Fatalf("mintInvite: %v", err)
+	}
+
+	guest := server.dial(t, "")
+	guest.joinWithInvite("INV004", "guest", token)
+	guest.expectError(ErrorCodeInvalidInviteToken)
+
+	guest.joinWithInvite("INV003", "guest", token)
+	guest.expect(WsMessageTypeLobbyJoined)
+}
+
+// requestInvite просит приглашение в лобби code от имени сессии resumeToken
+func (s *testServer) requestInvite(t *testing.T, code, resumeToken string) (int, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, s.url+"/lobbies/"+code+"/invite", nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	if resumeToken != "" {
+		req.Header.Set("Authorization", "Bearer "+resumeToken)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST invite: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if origin := resp.Header.Get("Access-Control-Allow-Origin"); origin != "" {
+		t.Errorf("got Access-Control-Allow-Origin %q without CheckOrigin", origin)
+	}
+
+	var body struct {
+		Token string `json:"token"`
+	}
+	json.NewDecoder(resp.Body).Decode(&body)
+	return resp.StatusCode, body.Token
+}
+
+// приглашение выдается только хосту лобби
+func TestInviteRequiresHost(t *testing.T) {
+	server := newTestServer(t, DefaultOptions())
+
+	host := server.dial(t, "")
+	host.createLobby("INV005", 4)
+
+	member := server.dial(t, "")
+	member.joinLobby("INV005", "member")
+	member.expect(WsMessageTypeLobbyJoined)
+
+	outsider := server.dial(t, "")
+
+	for _, tc := range []struct {
+		name        string
+		code        string
+		resumeToken string
+		status      int
+	}{
+		{"no token", "INV005", "", http.StatusUnauthorized},
+		{"unknown session", "INV005", "nope", http.StatusUnauthorized},
+		{"outsider", "INV005", outsider.resumeToken, http.StatusForbidden},
+		{"member", "INV005", member.resumeToken, http.StatusForbidden},
+		{"missing lobby", "NOPE00", host.resumeToken, http.StatusNotFound},
+	} {
+		if status, _ := server.requestInvite(t, tc.code, tc.resumeToken); status != tc.status {
+			t.Errorf("%s: got status %d, want %d", tc.name, status, tc.status)
+		}
+	}
+
+	status, token := server.requestInvite(t, "INV005", host.resumeToken)
+	if status != http.StatusOK || token == "" {
+		t.Fatalf("host got status %d and token %q", status, token)
+	}
+
+	outsider.joinWithInvite("INV005", "outsider", token)
+	outsider.expect(WsMessageTypeLobbyJoined)
+}
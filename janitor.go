@@ -0,0 +1,116 @@
+package guesswho
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// touch отмечает активность в лобби, можно вызывать не из хаба
+func (l *Lobby) touch() {
+	l.lastActivity.Store(time.Now().UnixNano())
+}
+
+func (l *Lobby) idle() time.Duration {
+	return time.Since(time.Unix(0, l.lastActivity.Load()))
+}
+
+// машиночитаемая причина закрытия лобби для LobbyClosed
+type LobbyCloseReason string
+
+const (
+	LobbyCloseReasonEmpty LobbyCloseReason = "Empty" // все ушли
+	LobbyCloseReasonIdle  LobbyCloseReason = "Idle"  // долго не было активности
+	LobbyCloseReasonAdmin LobbyCloseReason = "Admin" // закрыл администратор
+)
+
+// janitor периодически закрывает пустые и неактивные лобби
+func (s *Server) janitor(ctx context.Context) {
+	ticker := time.NewTicker(s.opts.JanitorInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		s.cleanupLobbies()
+		s.purgeExpiredInvites()
+		s.purgeExpiredIdempotencyKeys()
+	}
+}
+
+func (s *Server) cleanupLobbies() {
+	s.mu.Lock()
+	lobbies := make([]*Lobby, 0, len(s.Lobbies))
+	for _, lobby := range s.Lobbies {
+		lobbies = append(lobbies, lobby)
+	}
+	s.mu.Unlock()
+
+	for _, lobby := range lobbies {
+		lobby.do(func() {
+			idle := lobby.idle()
+			empty := len(lobby.Players)+len(lobby.Spectators) == 0
+
+			var reason LobbyCloseReason
+			switch {
+			case empty && idle > s.opts.LobbyEmptyTTL:
+				reason = LobbyCloseReasonEmpty
+			case idle > s.opts.LobbyIdleTTL:
+				reason = LobbyCloseReasonIdle
+			default:
+				return
+			}
+
+			log.Printf("INFO: janitor closed lobby %s, reason: %s", lobby.ID, reason)
+			lobby.close(reason)
+		})
+	}
+}
+
+// closeLobby убирает лобби с сервера и закрывает его
+func (s *Server) closeLobby(lobbyID string, reason LobbyCloseReason) bool {
+	err := s.withLobby(lobbyID, func(lobby *Lobby) error {
+		log.Printf("INFO: closed lobby %s, reason: %s", lobbyID, reason)
+		lobby.close(reason)
+		return nil
+	})
+	return err == nil
+}
+
+// close убирает лобби с сервера, уведомляет всех участников о закрытии с
+// указанием причины и убирает их из него. Вызывается из хаба, который после
+// этого события останавливается
+func (l *Lobby) close(reason LobbyCloseReason) {
+	l.server.mu.Lock()
+	if l.server.Lobbies[l.ID] == l {
+		delete(l.server.Lobbies, l.ID)
+	}
+	l.server.mu.Unlock()
+
+	msg := generateMsg(WsMessageTypeLobbyClosed, Payload{Lobby: &Lobby{ID: l.ID}, CloseReason: reason})
+
+	for _, member := range l.members(AudienceEveryone) {
+		if !isDisconnected(member) {
+			member.send(msg)
+		}
+		l.removePlayer(member)
+		member.IsHost = false
+		member.IsReady = false
+		member.IsSpectator = false
+	}
+
+	for _, queued := range l.queue {
+		queued.send(msg)
+	}
+	l.queue = nil
+
+	if l.autoStartTimer != nil {
+		l.autoStartTimer.Stop()
+		l.autoStartTimer = nil
+	}
+	l.stopped = true
+}
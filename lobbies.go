@@ -0,0 +1,157 @@
+package guesswho
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+const (
+	defaultLobbiesPageSize = 50
+	maxLobbiesPageSize     = 100
+)
+
+type LobbyListing struct {
+	ID           string        `json:"id"`
+	Name         string        `json:"name,omitempty"`
+	Region       string        `json:"region,omitempty"`
+	HostNickname string        `json:"hostNickname"`
+	PlayersCount int           `json:"playersCount"`
+	Settings     LobbySettings `json:"settings"`
+}
+
+// фильтры списка лобби, пустые поля не фильтруют
+type LobbyFilter struct {
+	GameMode      GameMode
+	Region        string
+	CharacterPack string
+	HasOpenSlot   bool
+}
+
+func (f LobbyFilter) matches(lobby *Lobby) bool {
+	switch {
+	case !lobby.IsPublic || lobby.IsLocked || lobby.InGame:
+		return false
+	case f.GameMode != "" && lobby.Settings.GameMode != f.GameMode:
+		return false
+	case f.Region != "" && lobby.Region != f.Region:
+		return false
+	case f.CharacterPack != "" && lobby.Settings.CharacterPack != f.CharacterPack:
+		return false
+	case f.HasOpenSlot && len(lobby.Players) >= lobby.Settings.MaxPlayers:
+		return false
+	}
+	return true
+}
+
+// publicLobbies возвращает страницу публичных лобби, подходящих под фильтр,
+// с ID больше cursor, и курсор следующей страницы (пустой, если это последняя)
+func (s *Server) publicLobbies(filter LobbyFilter, cursor string, limit int) ([]LobbyListing, string) {
+	s.mu.Lock()
+	lobbies := make([]*Lobby, 0, len(s.Lobbies))
+	for id, lobby := range s.Lobbies {
+		if id > cursor {
+			lobbies = append(lobbies, lobby)
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Slice(lobbies, func(i, j int) bool { return lobbies[i].ID < lobbies[j].ID })
+
+	listings := []LobbyListing{}
+	for _, lobby := range lobbies {
+		lobby.do(func() {
+			if !filter.matches(lobby) {
+				return
+			}
+
+			listing := LobbyListing{
+				ID:           lobby.ID,
+				Name:         lobby.Name,
+				Region:       lobby.Region,
+				PlayersCount: len(lobby.Players),
+				Settings:     lobby.Settings,
+			}
+			for _, player := range lobby.Players {
+				if player.IsHost {
+					listing.HostNickname = player.Nickname
+				}
+			}
+			listings = append(listings, listing)
+		})
+
+		if len(listings) > limit {
+			return listings[:limit], listings[limit-1].ID
+		}
+	}
+
+	return listings, ""
+}
+
+func (s *Server) handleLobbies(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte(`{"error": "Метод не поддерживается"}`))
+		return
+	}
+
+	query := r.URL.Query()
+
+	filter := LobbyFilter{
+		GameMode:      GameMode(query.Get("gameMode")),
+		Region:        query.Get("region"),
+		CharacterPack: query.Get("pack"),
+		HasOpenSlot:   query.Get("hasOpenSlot") != "false",
+	}
+
+	limit := defaultLobbiesPageSize
+	if rawLimit := query.Get("limit"); rawLimit != "" {
+		parsed, err := strconv.Atoi(rawLimit)
+		if err != nil || parsed < 1 || parsed > maxLobbiesPageSize {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "Некорректный limit"}`))
+			return
+		}
+		limit = parsed
+	}
+
+	lobbies, nextCursor := s.publicLobbies(filter, query.Get("cursor"), limit)
+
+	response := struct {
+		Lobbies    []LobbyListing `json:"lobbies"`
+		NextCursor string         `json:"nextCursor,omitempty"`
+	}{
+		Lobbies:    lobbies,
+		NextCursor: nextCursor,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleLobby - admin API отдельного лобби, пока только закрытие
+func (s *Server) handleLobby(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte(`{"error": "Метод не поддерживается"}`))
+		return
+	}
+
+	if !s.requireAdmin(w, r) {
+		return
+	}
+
+	if !s.closeLobby(r.PathValue("id"), LobbyCloseReasonAdmin) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "Лобби не найдено"}`))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
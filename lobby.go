@@ -0,0 +1,286 @@
+package guesswho
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+)
+
+type Audience int
+
+const (
+	AudienceEveryone Audience = iota // игроки и зрители
+	AudiencePlayers                  // только игроки, для приватных данных игры
+)
+
+// broadcast отправляет сообщение всем игрокам и зрителям лобби
+func (l *Lobby) broadcast(msg []byte) {
+	l.broadcastTo(AudienceEveryone, msg)
+}
+
+// broadcastTo отправляет сообщение аудитории лобби. Клиенты с дельтами
+// сначала получают LobbyStateDelta, а затем событие без полного лобби
+func (l *Lobby) broadcastTo(audience Audience, msg []byte) {
+	l.deliver(audience, l.publishDelta(), msg, nil)
+}
+
+// broadcastSnapshot рассылает событие с полным лобби о player, который только
+// что вошел или вернулся. Ему событие уходит целиком и без дельты даже с
+// дельтами: у него нет состояния, к которому их применять. Снимок собирается
+// после публикации дельты, чтобы revision в нем совпадала с остальными.
+// Лобби, где хоста не осталось, пока все игроки были без связи, получает хоста
+// здесь, когда player уже знает лобби. Возвращает полное событие, например для
+// ответа на запрос
+func (l *Lobby) broadcastSnapshot(msgType WsMessageType, player *Player) []byte {
+	delta := l.publishDelta()
+	msg := l.snapshot(msgType, player)
+	l.deliver(AudienceEveryone, delta, msg, player)
+	l.reassignHost()
+	return msg
+}
+
+// deliver рассылает delta и msg аудитории, full получает msg без сокращения
+func (l *Lobby) deliver(audience Audience, delta, msg []byte, full *Player) {
+	var compact []byte
+	for _, member := range l.members(audience) {
+		if isDisconnected(member) {
+			continue
+		}
+
+		if member == full || member.negotiated() < deltaProtocolVersion {
+			member.send(msg)
+			continue
+		}
+
+		if delta != nil {
+			member.send(delta)
+		}
+		if compact == nil {
+			compact = compactLobbyMsg(msg, l.ID, l.Revision)
+		}
+		member.send(compact)
+	}
+}
+
+func (l *Lobby) members(audience Audience) []*Player {
+	members := append([]*Player(nil), l.Players...)
+	if audience == AudienceEveryone {
+		members = append(members, l.Spectators...)
+	}
+	return members
+}
+
+// snapshot собирает полное состояние лобби (игроки, зрители, готовность,
+// настройки, идет ли игра); вызывается из хаба, поэтому оно согласованное
+func (l *Lobby) snapshot(msgType WsMessageType, player *Player) []byte {
+	return generateMsg(msgType, Payload{Lobby: l, Player: player})
+}
+
+// allReady проверяет, что все игроки кроме хоста готовы к старту. Игрок,
+// чье место придержано после обрыва, готовым не считается: без него игру не
+// начинаем
+func (l *Lobby) allReady() bool {
+	for _, player := range l.Players {
+		if isDisconnected(player) || (!player.IsHost && !player.IsReady) {
+			return false
+		}
+	}
+	return true
+}
+
+func handlePlayerReady(player *Player, ready bool) {
+	player.withLobby(func(lobby *Lobby) {
+		if player.IsSpectator {
+			player.sendError(ErrorCodeSpectatorAction, "ERROR: spectators can't take game actions")
+			return
+		}
+
+		if lobby.InGame {
+			player.sendError(ErrorCodeGameInProgress, "ERROR: game is already in progress")
+			return
+		}
+		player.IsReady = ready
+
+		log.Printf("INFO: player %s in lobby %s set ready to %v", player.ID, lobby.ID, ready)
+
+		lobby.broadcast(generateMsg(WsMessageTypePlayerReadyChanged, Payload{Lobby: lobby, Player: player}))
+
+		lobby.maybeAutoStart()
+	})
+}
+
+func handleStartGame(player *Player, _ json.RawMessage) {
+	player.withLobby(func(lobby *Lobby) {
+		if !player.IsHost {
+			player.sendError(ErrorCodeNotHost, "ERROR: only the host can start the game")
+			return
+		}
+
+		if err := lobby.startGame(); err != nil {
+			player.sendErr(err)
+		}
+	})
+}
+
+// startGame переводит лобби в игру, если все условия старта выполнены
+func (l *Lobby) startGame() error {
+	switch {
+	case l.InGame:
+		return protocolError(ErrorCodeGameInProgress, "ERROR: game is already in progress")
+	case len(l.Players) < 2:
+		return protocolError(ErrorCodeNotEnoughPlayers, "ERROR: not enough players to start the game")
+	case !l.allReady():
+		return protocolError(ErrorCodePlayersNotReady, "ERROR: not all players are ready")
+	}
+	l.InGame = true
+
+	log.Printf("INFO: game started in lobby %s", l.ID)
+	l.logEvent(LobbyEventGameStarted, nil, "")
+
+	l.broadcast(generateMsg(WsMessageTypeGameStarted, Payload{Lobby: l}))
+
+	return nil
+}
+
+// removePlayer убирает игрока из лобби, возвращает false если его там не было
+func (l *Lobby) removePlayer(player *Player) bool {
+	l.touch()
+
+	for i, lobbyPlayer := range l.Players {
+		if lobbyPlayer == player {
+			l.Players = append(l.Players[:i], l.Players[i+1:]...)
+			player.setLobby(nil)
+			return true
+		}
+	}
+	for i, spectator := range l.Spectators {
+		if spectator == player {
+			l.Spectators = append(l.Spectators[:i], l.Spectators[i+1:]...)
+			player.setLobby(nil)
+			return true
+		}
+	}
+	return false
+}
+
+func (l *Lobby) findPlayer(playerID string) *Player {
+	for _, lobbyPlayer := range l.Players {
+		if lobbyPlayer.ID == playerID {
+			return lobbyPlayer
+		}
+	}
+	for _, spectator := range l.Spectators {
+		if spectator.ID == playerID {
+			return spectator
+		}
+	}
+	return nil
+}
+
+func handleKickPlayer(player *Player, payloadJson json.RawMessage) {
+	var request KickPlayerRequest
+	if !decodeRequest(player, payloadJson, &request) {
+		return
+	}
+
+	player.withLobby(func(lobby *Lobby) {
+		if !player.IsHost {
+			player.sendError(ErrorCodeNotHost, "ERROR: only the host can kick players")
+			return
+		}
+
+		if request.Player.ID == player.ID {
+			player.sendError(ErrorCodeInvalidRequest, "ERROR: invalid player to kick")
+			return
+		}
+
+		kicked := lobby.findPlayer(request.Player.ID)
+		if kicked == nil || !lobby.removePlayer(kicked) {
+			player.sendError(ErrorCodePlayerNotFound, fmt.Sprintf("ERROR: player with id %s is not in the lobby", request.Player.ID))
+			return
+		}
+
+		if request.Ban {
+			if lobby.banned == nil {
+				lobby.banned = make(map[string]struct{})
+			}
+			lobby.banned[kicked.ID] = struct{}{}
+		}
+
+		log.Printf("INFO: player %s kicked from lobby %s by host %s, banned: %v", kicked.ID, lobby.ID, player.ID, request.Ban)
+		if request.Ban {
+			lobby.logEvent(LobbyEventPlayerKicked, kicked, "banned")
+		} else {
+			lobby.logEvent(LobbyEventPlayerKicked, kicked, "")
+		}
+
+		kicked.IsReady = false
+		kicked.send(generateMsg(WsMessageTypeKickedFromLobby, Payload{Lobby: &Lobby{ID: lobby.ID}}))
+		lobby.broadcast(generateMsg(WsMessageTypePlayerKicked, Payload{Lobby: lobby, Player: kicked}))
+
+		if kicked.IsSpectator {
+			kicked.IsSpectator = false
+			lobby.broadcastSpectators()
+		}
+
+		lobby.promoteQueued()
+		lobby.maybeAutoStart()
+	})
+}
+
+func handleLockLobby(player *Player, locked bool) {
+	player.withLobby(func(lobby *Lobby) {
+		if !player.IsHost {
+			player.sendError(ErrorCodeNotHost, "ERROR: only the host can lock the lobby")
+			return
+		}
+
+		lobby.IsLocked = locked
+
+		log.Printf("INFO: lobby %s locked set to %v", lobby.ID, locked)
+		lobby.logEvent(LobbyEventLockChanged, player, strconv.FormatBool(locked))
+
+		lobby.broadcast(generateMsg(WsMessageTypeLobbyLockChanged, Payload{Lobby: lobby}))
+
+		if !locked {
+			lobby.promoteQueued()
+		}
+	})
+}
+
+// leaveLobby убирает игрока из его лобби, сообщает остальным и при необходимости
+// передает хоста следующему игроку
+func leaveLobby(player *Player) {
+	if lobby := player.lobby(); lobby != nil {
+		lobby.do(func() { lobby.leave(player) })
+	}
+}
+
+// leave - leaveLobby в хабе лобби, игрока там уже может не быть
+func (l *Lobby) leave(player *Player) {
+	if !l.removePlayer(player) {
+		return
+	}
+
+	wasHost := player.IsHost
+	player.IsHost = false
+	player.IsReady = false
+
+	log.Printf("INFO: player %s left lobby %s", player.ID, l.ID)
+	l.logEvent(LobbyEventPlayerLeft, player, "")
+
+	l.broadcast(generateMsg(WsMessageTypePlayerLeft, Payload{Lobby: l, Player: player}))
+
+	if player.IsSpectator {
+		player.IsSpectator = false
+		l.broadcastSpectators()
+	}
+
+	if wasHost {
+		l.reassignHost()
+	}
+
+	l.promoteQueued()
+	l.maybeAutoStart()
+}
@@ -0,0 +1,74 @@
+package guesswho
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+)
+
+// третий игрок с дельтами входит в лобби, которое уже опубликовало несколько
+// ревизий: LobbyJoined должен принести ему лобби целиком, а не только то, что
+// изменилось с последней рассылки
+func TestLobbyJoinedCarriesFullLobbyForDeltaClient(t *testing.T) {
+	server := newTestServer(t, DefaultOptions())
+
+	host := server.dial(t, "protocolVersion=3")
+	host.createLobby("FULL01", 4)
+
+	second := server.dial(t, "protocolVersion=3")
+	second.joinLobby("FULL01", "bob")
+	second.expect(WsMessageTypeLobbyJoined)
+
+	host.send(WsMessageTypeUpdateLobbySettings, `{"settings":{"turnTimerSeconds":90,"gameMode":"Classic","characterPack":"animals","maxPlayers":4}}`)
+	host.expect(WsMessageTypeLobbySettingsUpdated)
+
+	third := server.dial(t, "protocolVersion=3")
+	third.joinLobby("FULL01", "carl")
+	joined := third.expect(WsMessageTypeLobbyJoined).decode(t)
+
+	lobby := joined.Lobby
+	if lobby == nil {
+		t.Fatal("LobbyJoined has no lobby")
+	}
+	if len(lobby.Players) != 3 {
+		t.Fatalf("got %d players, want 3", len(lobby.Players))
+	}
+	if !lobby.Players[0].IsHost || lobby.Players[0].ID != host.id {
+		t.Errorf("first player is not the host: %+v", lobby.Players[0])
+	}
+	if lobby.Settings.TurnTimerSeconds != 90 || lobby.Settings.CharacterPack != "animals" || lobby.Settings.MaxPlayers != 4 {
+		t.Errorf("got settings %+v, want the updated ones", lobby.Settings)
+	}
+
+	// остальные получают сокращенное событие с той же ревизией
+	compact := second.expect(WsMessageTypeLobbyJoined).decode(t)
+	if compact.Lobby == nil || compact.Lobby.Revision != lobby.Revision {
+		t.Errorf("joiner got revision %d, other members got %+v", lobby.Revision, compact.Lobby)
+	}
+}
+
+// забаненный игрок не входит обратно ни с того же соединения, ни вернувшись
+// на новое по resume token
+func TestKickBanRefusesJoin(t *testing.T) {
+	server := newTestServer(t, DefaultOptions())
+
+	host := server.dial(t, "")
+	host.createLobby("BAN001", 4)
+
+	member := server.dial(t, "")
+	member.joinLobby("BAN001", "member")
+	member.expect(WsMessageTypeLobbyJoined)
+
+	host.send(WsMessageTypeKickPlayer, fmt.Sprintf(`{"player":{"id":%q},"ban":true}`, member.id))
+	member.expect(WsMessageTypeKickedFromLobby)
+
+	member.joinLobby("BAN001", "member")
+	member.expectError(ErrorCodeBanned)
+
+	resumed := server.dial(t, "resumeToken="+url.QueryEscape(member.resumeToken))
+	if resumed.id != member.id {
+		t.Fatalf("got id %s, want %s", resumed.id, member.id)
+	}
+	resumed.joinLobby("BAN001", "member")
+	resumed.expectError(ErrorCodeBanned)
+}
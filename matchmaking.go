@@ -0,0 +1,295 @@
+package guesswho
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"math"
+	"sync"
+	"time"
+)
+
+// matchStatsWindow - за какой период последних матчей оценивается ожидание в очереди
+const matchStatsWindow = 10 * time.Minute
+
+type matchRequest struct {
+	player   *Player
+	gameMode GameMode
+	region   string
+	rtt      time.Duration // сглаженный RTT на момент постановки в очередь
+	queuedAt time.Time
+}
+
+// compatible проверяет, можно ли свести двух игроков: режим должен совпадать,
+// а регион - пока оба не прождали дольше regionPreferenceWindow
+func (r matchRequest) compatible(other matchRequest, regionPreferenceWindow time.Duration) bool {
+	if r.gameMode != other.gameMode {
+		return false
+	}
+
+	if r.region == "" || other.region == "" || r.region == other.region {
+		return true
+	}
+
+	return time.Since(r.queuedAt) > regionPreferenceWindow && time.Since(other.queuedAt) > regionPreferenceWindow
+}
+
+// matchStats - сводка очереди для Capacity. queue принадлежит горутине run,
+// поэтому наружу ее длина публикуется отдельно, под своим мьютексом
+type matchStats struct {
+	mu        sync.Mutex
+	queued    int
+	matchedAt []time.Time // матчи не старше matchStatsWindow
+}
+
+func (st *matchStats) setQueued(queued int) {
+	st.mu.Lock()
+	st.queued = queued
+	st.mu.Unlock()
+}
+
+func (st *matchStats) recordMatch(at time.Time) {
+	st.mu.Lock()
+	st.matchedAt = append(st.prune(at), at)
+	st.mu.Unlock()
+}
+
+// prune отбрасывает матчи старше matchStatsWindow, вызывается под st.mu
+func (st *matchStats) prune(now time.Time) []time.Time {
+	i := 0
+	for i < len(st.matchedAt) && now.Sub(st.matchedAt[i]) > matchStatsWindow {
+		i++
+	}
+	st.matchedAt = st.matchedAt[i:]
+	return st.matchedAt
+}
+
+// estimatedWait оценивает ожидание нового игрока по закону Литтла: из
+// очереди уходит по два игрока за матч, и перед новым в ней стоит queued
+// человек. Без матчей за последние matchStatsWindow оценки нет, тогда 0.
+// Оценка не превышает limit - дольше игрок в очереди не простоит
+func (st *matchStats) estimatedWait(now time.Time, limit time.Duration) time.Duration {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	matches := len(st.prune(now))
+	if matches == 0 {
+		return 0
+	}
+
+	wait := time.Duration(st.queued+1) * matchStatsWindow / time.Duration(2*matches)
+	return min(wait, limit)
+}
+
+// очередь быстрого поиска, состоянием владеет только горутина run
+type Matchmaker struct {
+	server  *Server
+	enqueue chan matchRequest
+	cancel  chan *Player
+	queue   []matchRequest
+	stats   matchStats
+}
+
+func newMatchmaker(server *Server) *Matchmaker {
+	return &Matchmaker{
+		server:  server,
+		enqueue: make(chan matchRequest),
+		cancel:  make(chan *Player),
+	}
+}
+
+func (m *Matchmaker) run(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case request := <-m.enqueue:
+			if m.remove(request.player) {
+				log.Printf("INFO: player %s re-queued for matchmaking", request.player.id())
+			}
+			m.queue = append(m.queue, request)
+			request.player.send(generateMsg(WsMessageTypeMatchmakingQueued, Payload{Settings: &LobbySettings{GameMode: request.gameMode}}))
+			m.match()
+		case player := <-m.cancel:
+			if m.remove(player) {
+				player.send(generateMsg(WsMessageTypeMatchmakingCancelled, Payload{}))
+			}
+		case <-ticker.C:
+			m.expire()
+		}
+
+		m.stats.setQueued(len(m.queue))
+	}
+}
+
+// estimatedWaitSeconds - оценка ожидания в быстром поиске с округлением вверх до
+// секунды, 0 - если оценить пока не по чему
+func (m *Matchmaker) estimatedWaitSeconds() int {
+	wait := m.stats.estimatedWait(time.Now(), m.server.opts.MatchmakingTimeout)
+	return int(math.Ceil(wait.Seconds()))
+}
+
+func (m *Matchmaker) remove(player *Player) bool {
+	for i, request := range m.queue {
+		if request.player == player {
+			m.queue = append(m.queue[:i], m.queue[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// expire выкидывает из очереди отключившихся, уже попавших в лобби и тех,
+// кто ждет дольше Options.MatchmakingTimeout
+func (m *Matchmaker) expire() {
+	queue := m.queue[:0]
+	for _, request := range m.queue {
+		switch {
+		case isDisconnected(request.player), request.player.lobby() != nil:
+		case time.Since(request.queuedAt) > m.server.opts.MatchmakingTimeout:
+			log.Printf("INFO: matchmaking timed out for player %s", request.player.id())
+			request.player.send(generateMsg(WsMessageTypeMatchmakingTimedOut, Payload{}))
+		default:
+			queue = append(queue, request)
+		}
+	}
+	m.queue = queue
+}
+
+// match сводит первую пару совместимых игроков из очереди
+func (m *Matchmaker) match() {
+	m.expire()
+
+	for i := 0; i < len(m.queue); i++ {
+		for j := i + 1; j < len(m.queue); j++ {
+			if !m.queue[i].compatible(m.queue[j], m.server.opts.RegionPreferenceWindow) {
+				continue
+			}
+
+			host, guest := m.queue[i], m.queue[j]
+			m.queue = append(m.queue[:j], m.queue[j+1:]...)
+			m.queue = append(m.queue[:i], m.queue[i+1:]...)
+
+			m.start(host, guest)
+			return
+		}
+	}
+}
+
+// start сажает пару в лобби. Если матч не состоялся, оба возвращаются в начало
+// очереди с прежним временем ожидания, а тот, из-за кого он сорвался (успел
+// войти в другое лобби или отключился), выпадает из нее в expire
+func (m *Matchmaker) start(host, guest matchRequest) {
+	if err := m.server.startMatch(host, guest); err != nil {
+		log.Printf("ERROR: can't start match for players %s and %s, error: %v", host.player.id(), guest.player.id(), err)
+
+		m.queue = append([]matchRequest{host, guest}, m.queue...)
+		m.expire()
+		return
+	}
+
+	m.stats.recordMatch(time.Now())
+}
+
+func (s *Server) startMatch(host, guest matchRequest) error {
+	settings := defaultLobbySettings()
+	settings.GameMode = host.gameMode
+
+	lobby, err := s.createLobby(host.player, LobbyOptions{Region: matchRegion(host, guest), Settings: &settings})
+	if err != nil {
+		return err
+	}
+
+	lobby.do(func() {
+		if err = lobby.join(guest.player, false); err != nil {
+			// хост о лобби еще ничего не знает, поэтому убираем его молча и
+			// закрываем уже пустое лобби
+			lobby.removePlayer(host.player)
+			host.player.IsHost = false
+			lobby.close(LobbyCloseReasonEmpty)
+			return
+		}
+
+		for _, player := range lobby.Players {
+			player.IsReady = true
+		}
+		lobby.InGame = true
+
+		log.Printf("INFO: matched players %s and %s in lobby %s", host.player.id(), guest.player.id(), lobby.ID)
+		lobby.logEvent(LobbyEventGameStarted, nil, "matchmaking")
+
+		lobby.broadcast(generateMsg(WsMessageTypeMatchFound, Payload{Lobby: lobby}))
+		lobby.broadcast(generateMsg(WsMessageTypeGameStarted, Payload{Lobby: lobby}))
+	})
+
+	return err
+}
+
+// matchRegion выбирает регион лобби для пары из разных регионов: регион
+// игрока с худшей связью, ему ближний сервер нужнее
+func matchRegion(host, guest matchRequest) string {
+	if host.region == "" || (guest.region != "" && guest.rtt > host.rtt) {
+		return guest.region
+	}
+	return host.region
+}
+
+func isDisconnected(player *Player) bool {
+	select {
+	case <-player.Done:
+		return true
+	default:
+		return false
+	}
+}
+
+func handleFindMatch(player *Player, payloadJson json.RawMessage) {
+	var request FindMatchRequest
+	if !decodeRequest(player, payloadJson, &request) {
+		return
+	}
+
+	if player.lobby() != nil {
+		player.sendError(ErrorCodeAlreadyInLobby, "ERROR: player is already in a lobby")
+		return
+	}
+
+	if err := player.applyProfile(request.Player); err != nil {
+		player.sendErr(err)
+		return
+	}
+
+	gameMode := GameModeClassic
+	if request.Settings != nil && request.Settings.GameMode != "" {
+		gameMode = request.Settings.GameMode
+	}
+
+	var region string
+	if request.Lobby != nil {
+		region = request.Lobby.Region
+	}
+
+	queued := matchRequest{
+		player:   player,
+		gameMode: gameMode,
+		region:   region,
+		rtt:      player.heartbeat.smoothedRTT(),
+		queuedAt: time.Now(),
+	}
+
+	// при остановке сервера matchmaker уже не читает очередь
+	select {
+	case player.server.matchmaker.enqueue <- queued:
+	case <-player.Done:
+	}
+}
+
+func handleCancelFindMatch(player *Player, _ json.RawMessage) {
+	select {
+	case player.server.matchmaker.cancel <- player:
+	case <-player.Done:
+	}
+}
@@ -0,0 +1,184 @@
+package guesswho
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// newMatchPlayer - игрок без соединения: сообщения копятся в его SendChan
+func newMatchPlayer(t *testing.T, s *Server, nickname string) matchRequest {
+	t.Helper()
+
+	player, _ := s.newPlayer(context.Background(), nil, url.Values{})
+	player.Nickname = nickname
+	return matchRequest{player: player, gameMode: GameModeClassic, queuedAt: time.Now()}
+}
+
+// sentTypes возвращает типы сообщений, уже отправленных игроку
+func sentTypes(t *testing.T, player *Player) []WsMessageType {
+	t.Helper()
+
+	var types []WsMessageType
+	for {
+		select {
+		case msg := <-player.SendChan:
+			types = append(types, testMessageType(t, msg))
+		default:
+			return types
+		}
+	}
+}
+
+func queuedPlayers(m *Matchmaker) []*Player {
+	var players []*Player
+	for _, request := range m.queue {
+		players = append(players, request.player)
+	}
+	return players
+}
+
+// гость успел войти в другое лобби: лобби хоста закрывается, хост ничего о нем
+// не узнает и остается в очереди, гость из нее выпадает
+func TestMatchmakerGuestJoinFailure(t *testing.T) {
+	server := newTestServer(t, DefaultOptions())
+	m := newMatchmaker(server.Server)
+
+	host := newMatchPlayer(t, server.Server, "host")
+	guest := newMatchPlayer(t, server.Server, "guest")
+
+	other, err := server.createLobby(guest.player, LobbyOptions{Code: "OTHER1"})
+	if err != nil {
+		t.Fatalf("createLobby: %v", err)
+	}
+
+	m.start(host, guest)
+
+	if lobby := host.player.lobby(); lobby != nil {
+		t.Fatalf("host is left in lobby %s", lobby.ID)
+	}
+	if host.player.IsHost {
+		t.Error("host still has IsHost set")
+	}
+	if guest.player.lobby() != other {
+		t.Error("guest was moved out of their own lobby")
+	}
+
+	server.mu.Lock()
+	lobbiesCount := len(server.Lobbies)
+	server.mu.Unlock()
+	if lobbiesCount != 1 {
+		t.Errorf("got %d lobbies, want only the guest's one", lobbiesCount)
+	}
+
+	for _, msgType := range sentTypes(t, host.player) {
+		if msgType == WsMessageTypeLobbyCreated || msgType == WsMessageTypeMatchFound || msgType == WsMessageTypeLobbyClosed {
+			t.Errorf("host got %s for a match that didn't happen", msgType)
+		}
+	}
+
+	if queued := queuedPlayers(m); len(queued) != 1 || queued[0] != host.player {
+		t.Errorf("got queue %v, want only the host", queued)
+	}
+}
+
+// хост успел войти в другое лобби: лобби для матча не создается, гость
+// остается в очереди
+func TestMatchmakerHostCreateFailure(t *testing.T) {
+	server := newTestServer(t, DefaultOptions())
+	m := newMatchmaker(server.Server)
+
+	host := newMatchPlayer(t, server.Server, "host")
+	guest := newMatchPlayer(t, server.Server, "guest")
+
+	other, err := server.createLobby(host.player, LobbyOptions{Code: "OTHER1"})
+	if err != nil {
+		t.Fatalf("createLobby: %v", err)
+	}
+
+	m.start(host, guest)
+
+	if host.player.lobby() != other {
+		t.Error("host was moved out of their own lobby")
+	}
+	if lobby := guest.player.lobby(); lobby != nil {
+		t.Fatalf("guest is in lobby %s", lobby.ID)
+	}
+
+	if queued := queuedPlayers(m); len(queued) != 1 || queued[0] != guest.player {
+		t.Errorf("got queue %v, want only the guest", queued)
+	}
+}
+
+// без помех пара попадает в одно лобби и сразу в игру
+func TestMatchmakerStart(t *testing.T) {
+	server := newTestServer(t, DefaultOptions())
+	m := newMatchmaker(server.Server)
+
+	host := newMatchPlayer(t, server.Server, "host")
+	guest := newMatchPlayer(t, server.Server, "guest")
+
+	m.start(host, guest)
+
+	lobby := host.player.lobby()
+	if lobby == nil || guest.player.lobby() != lobby {
+		t.Fatal("players are not in the same lobby")
+	}
+	if len(m.queue) != 0 {
+		t.Errorf("got %d queued players, want 0", len(m.queue))
+	}
+
+	for _, request := range []matchRequest{host, guest} {
+		types := sentTypes(t, request.player)
+		if len(types) != 2 || types[0] != WsMessageTypeMatchFound || types[1] != WsMessageTypeGameStarted {
+			t.Errorf("player %s got %v, want MatchFound and GameStarted", request.player.Nickname, types)
+		}
+	}
+}
+
+func TestMatchStatsEstimatedWait(t *testing.T) {
+	var stats matchStats
+	now := time.Now()
+
+	if wait := stats.estimatedWait(now, time.Hour); wait != 0 {
+		t.Errorf("got %v without matches, want 0", wait)
+	}
+
+	// 10 матчей за окно: из очереди уходит 20 игроков за matchStatsWindow
+	for i := 0; i < 10; i++ {
+		stats.recordMatch(now.Add(-matchStatsWindow / 2))
+	}
+	if wait, want := stats.estimatedWait(now, time.Hour), matchStatsWindow/20; wait != want {
+		t.Errorf("got %v with an empty queue, want %v", wait, want)
+	}
+
+	stats.setQueued(3)
+	if wait, want := stats.estimatedWait(now, time.Hour), 4*matchStatsWindow/20; wait != want {
+		t.Errorf("got %v with 3 queued, want %v", wait, want)
+	}
+	if wait := stats.estimatedWait(now, time.Second); wait != time.Second {
+		t.Errorf("got %v, want the estimate capped at 1s", wait)
+	}
+
+	if wait := stats.estimatedWait(now.Add(matchStatsWindow), time.Hour); wait != 0 {
+		t.Errorf("got %v after the matches left the window, want 0", wait)
+	}
+}
+
+func TestCapacityEstimatedWaitAfterMatch(t *testing.T) {
+	server := newTestServer(t, DefaultOptions())
+	m := newMatchmaker(server.Server)
+	server.matchmaker = m
+
+	if wait := server.capacity().EstimatedWaitSeconds; wait != 0 {
+		t.Fatalf("got estimated wait %ds before any match, want 0", wait)
+	}
+
+	m.start(newMatchPlayer(t, server.Server, "host"), newMatchPlayer(t, server.Server, "guest"))
+
+	want := int(min(matchStatsWindow/2, server.opts.MatchmakingTimeout) / time.Second)
+	if wait := server.capacity().EstimatedWaitSeconds; wait != want {
+		t.Errorf("got estimated wait %ds after one match, want %ds", wait, want)
+	}
+}
@@ -0,0 +1,23 @@
+package guesswho
+
+// лимиты payload по типам сообщений, остальным хватает defaultMaxPayloadSize
+const defaultMaxPayloadSize = 1024
+
+var maxPayloadSizes = map[WsMessageType]int{
+	WsMessageTypeCreateLobby:         2048, // имя, регион и настройки лобби
+	WsMessageTypeUpdateLobbySettings: 2048,
+	WsMessageTypeJoinLobby:           2048, // может содержать invite token
+}
+
+// checkPayloadSize отклоняет payload больше лимита его типа до разбора в json.Unmarshal
+func checkPayloadSize(msg WsMessage) error {
+	limit, exists := maxPayloadSizes[msg.Type]
+	if !exists {
+		limit = defaultMaxPayloadSize
+	}
+
+	if len(msg.Payload) > limit {
+		return protocolError(ErrorCodeMessageTooLarge, "ERROR: %s payload is %d bytes, limit is %d", msg.Type, len(msg.Payload), limit)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package guesswho
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+)
+
+// брендинг и правила конкретного развертывания
+type Meta struct {
+	ServerName  string `json:"serverName"`
+	WelcomeText string `json:"welcomeText,omitempty"`
+	Rules       string `json:"rules,omitempty"` // markdown
+	HouseRules  string `json:"houseRules,omitempty"`
+	Contact     string `json:"contact,omitempty"`
+}
+
+// текущий брендинг, начальный берется из Options.Meta
+type metaStore struct {
+	Meta
+	mu sync.Mutex
+}
+
+// isAdmin проверяет bearer токен admin API, пустой Options.AdminToken отключает admin API
+func (s *Server) isAdmin(r *http.Request) bool {
+	return s.opts.AdminToken != "" && r.Header.Get("Authorization") == "Bearer "+s.opts.AdminToken
+}
+
+// requireAdmin пропускает только запросы admin API с верным токеном. На
+// отсутствующий и неверный токен всех admin эндпоинтов одинаково отвечает 401
+func (s *Server) requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if s.isAdmin(r) {
+		return true
+	}
+
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	w.WriteHeader(http.StatusUnauthorized)
+	w.Write([]byte(`{"error": "Нет доступа"}`))
+	return false
+}
+
+func (s *Server) handleMeta(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		if !s.requireAdmin(w, r) {
+			return
+		}
+
+		var m Meta
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+			log.Printf("ERROR: can't decode meta update, error: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "Некорректный JSON"}`))
+			return
+		}
+
+		s.meta.mu.Lock()
+		s.meta.Meta = m
+		s.meta.mu.Unlock()
+
+		log.Printf("INFO: meta updated: %+v", m)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte(`{"error": "Метод не поддерживается"}`))
+		return
+	}
+
+	s.meta.mu.Lock()
+	response := s.meta.Meta
+	s.meta.mu.Unlock()
+
+	json.NewEncoder(w).Encode(response)
+}
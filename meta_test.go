@@ -0,0 +1,60 @@
+package guesswho
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// без токена и с неверным токеном все admin эндпоинты отвечают одинаково:
+// 401 с WWW-Authenticate: Bearer
+func TestAdminEndpointsRequireToken(t *testing.T) {
+	opts := DefaultOptions()
+	opts.AdminToken = "secret"
+	server := newTestServer(t, opts)
+
+	for _, endpoint := range []struct {
+		method, path, body string
+		adminStatus        int
+	}{
+		{http.MethodGet, "/bandwidth", "", http.StatusOK},
+		{http.MethodPut, "/meta", `{"serverName": "Test"}`, http.StatusOK},
+		{http.MethodDelete, "/lobbies/missing", "", http.StatusNotFound},
+	} {
+		for _, tc := range []struct {
+			name          string
+			authorization string
+			status        int
+		}{
+			{"no token", "", http.StatusUnauthorized},
+			{"wrong token", "Bearer nope", http.StatusUnauthorized},
+			{"admin", "Bearer secret", endpoint.adminStatus},
+		} {
+			req, err := http.NewRequest(endpoint.method, server.url+endpoint.path, strings.NewReader(endpoint.body))
+			if err != nil {
+				t.Fatalf("NewRequest: %v", err)
+			}
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("%s %s: %v", endpoint.method, endpoint.path, err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tc.status {
+				t.Errorf("%s %s, %s: got status %d, want %d", endpoint.method, endpoint.path, tc.name, resp.StatusCode, tc.status)
+			}
+
+			challenge := resp.Header.Get("WWW-Authenticate")
+			if tc.status == http.StatusUnauthorized && challenge != "Bearer" {
+				t.Errorf("%s %s, %s: got WWW-Authenticate %q, want Bearer", endpoint.method, endpoint.path, tc.name, challenge)
+			}
+			if tc.status != http.StatusUnauthorized && challenge != "" {
+				t.Errorf("%s %s, %s: got WWW-Authenticate %q for an authorized request", endpoint.method, endpoint.path, tc.name, challenge)
+			}
+		}
+	}
+}
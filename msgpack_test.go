@@ -0,0 +1,152 @@
+package guesswho
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// decodeNumbers разбирает JSON с числами как json.Number, чтобы сравнивать
+// большие целые без потери точности
+func decodeNumbers(t *testing.T, data []byte) any {
+	t.Helper()
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		t.Fatalf("can't decode %s: %v", data, err)
+	}
+	return value
+}
+
+func jsonArray(n int) string {
+	items := make([]string, n)
+	for i := range items {
+		items[i] = fmt.Sprint(i)
+	}
+	return "[" + strings.Join(items, ",") + "]"
+}
+
+func jsonObject(n int) string {
+	entries := make([]string, n)
+	for i := range entries {
+		entries[i] = fmt.Sprintf(`"key%02d":%d`, i, i)
+	}
+	return "{" + strings.Join(entries, ",") + "}"
+}
+
+func TestMsgpackRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		json string
+		head []byte // ожидаемое начало MessagePack
+	}{
+		{"nil", `null`, []byte{0xc0}},
+		{"true", `true`, []byte{0xc3}},
+		{"false", `false`, []byte{0xc2}},
+		{"positive fixint", `127`, []byte{0x7f}},
+		{"negative fixint", `-32`, []byte{0xe0}},
+		{"int8", `-33`, []byte{0xd0, 0xdf}},
+		{"int16", `128`, []byte{0xd1, 0x00, 0x80}},
+		{"negative int16", `-129`, []byte{0xd1, 0xff, 0x7f}},
+		{"int32", `40000`, []byte{0xd2, 0x00, 0x00, 0x9c, 0x40}},
+		{"int64", `9007199254740993`, []byte{0xd3, 0x00, 0x20, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01}},
+		{"min int64", `-9223372036854775808`, []byte{0xd3, 0x80}},
+		{"float64", `-0.25`, []byte{0xcb, 0xbf, 0xd0}},
+		{"pi", `3.141592653589793`, []byte{0xcb}},
+		{"empty string", `""`, []byte{0xa0}},
+		{"fixstr", `"` + strings.Repeat("a", 31) + `"`, []byte{0xbf}},
+		{"str8", `"` + strings.Repeat("a", 32) + `"`, []byte{0xd9, 32}},
+		{"str16", `"` + strings.Repeat("a", 256) + `"`, []byte{0xda, 0x01, 0x00}},
+		{"str32", `"` + strings.Repeat("a", 65536) + `"`, []byte{0xdb, 0x00, 0x01, 0x00, 0x00}},
+		{"unicode", `"Привет, мир"`, []byte{0xb4}},
+		{"fixarray", jsonArray(15), []byte{0x9f}},
+		{"array16", jsonArray(16), []byte{0xdc, 0x00, 0x10}},
+		{"fixmap", jsonObject(15), []byte{0x8f}},
+		{"map16", jsonObject(16), []byte{0xde, 0x00, 0x10}},
+		{"nested", `{"type":"LobbyJoined","payload":{"lobby":{"id":"ABC123","players":[{"id":"p1","isReady":false}],"revision":42},"ratio":0.5,"missing":null}}`, []byte{0x82}},
+	} {
+		packed, err := jsonToMsgpack([]byte(tc.json))
+		if err != nil {
+			t.Errorf("%s: jsonToMsgpack: %v", tc.name, err)
+			continue
+		}
+		if !bytes.HasPrefix(packed, tc.head) {
+			t.Errorf("%s: got % x, want prefix % x", tc.name, packed[:min(len(packed), 10)], tc.head)
+		}
+
+		unpacked, err := msgpackToJSON(packed)
+		if err != nil {
+			t.Errorf("%s: msgpackToJSON: %v", tc.name, err)
+			continue
+		}
+		if got, want := decodeNumbers(t, unpacked), decodeNumbers(t, []byte(tc.json)); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %s after round trip", tc.name, unpacked[:min(len(unpacked), 100)])
+		}
+	}
+}
+
+// форматы, которые наш кодировщик не пишет, но клиенты присылать могут
+func TestMsgpackDecodeForeignFormats(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		data []byte
+		json string
+	}{
+		{"uint8", []byte{0xcc, 0xff}, `255`},
+		{"uint16", []byte{0xcd, 0xff, 0xff}, `65535`},
+		{"uint32", []byte{0xce, 0xff, 0xff, 0xff, 0xff}, `4294967295`},
+		{"uint64", []byte{0xcf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, `18446744073709551615`},
+		{"float32", []byte{0xca, 0x3f, 0xc0, 0x00, 0x00}, `1.5`},
+		{"bin8", []byte{0xc4, 0x02, 'h', 'i'}, `"hi"`},
+		{"array32", []byte{0xdd, 0x00, 0x00, 0x00, 0x01, 0xc3}, `[true]`},
+		{"map32", []byte{0xdf, 0x00, 0x00, 0x00, 0x01, 0xa1, 'a', 0x01}, `{"a":1}`},
+	} {
+		unpacked, err := msgpackToJSON(tc.data)
+		if err != nil {
+			t.Errorf("%s: msgpackToJSON: %v", tc.name, err)
+			continue
+		}
+		if got, want := decodeNumbers(t, unpacked), decodeNumbers(t, []byte(tc.json)); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %s, want %s", tc.name, unpacked, tc.json)
+		}
+	}
+}
+
+func TestMsgpackDecodeInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"truncated fixstr", []byte{0xa5, 'a', 'b'}},
+		{"truncated str8 length", []byte{0xd9}},
+		{"truncated int16", []byte{0xd1, 0x00}},
+		{"truncated float64", []byte{0xcb, 0x00, 0x00}},
+		{"short array", []byte{0x92, 0x01}},
+		{"short map", []byte{0x81, 0xa1, 'a'}},
+		{"huge array length", []byte{0xdd, 0xff, 0xff, 0xff, 0xff}},
+		{"huge str length", []byte{0xdb, 0xff, 0xff, 0xff, 0xff, 'a'}},
+		{"non-string key", []byte{0x81, 0x01, 0x02}},
+		{"unsupported format", []byte{0xc1}},
+		{"ext", []byte{0xd4, 0x01, 0x02}},
+		{"trailing bytes", []byte{0x01, 0x02}},
+	} {
+		if unpacked, err := msgpackToJSON(tc.data); err == nil {
+			t.Errorf("%s: got %s, want an error", tc.name, unpacked)
+		}
+	}
+}
+
+func TestMsgpackEncodeInvalidJSON(t *testing.T) {
+	for _, data := range []string{``, `{"a":`, `[1,2`, `tru`} {
+		if _, err := jsonToMsgpack([]byte(data)); err == nil {
+			t.Errorf("%q: want an error", data)
+		}
+	}
+}
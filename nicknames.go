@@ -0,0 +1,54 @@
+package guesswho
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	minNicknameLength = 2
+	maxNicknameLength = 20
+)
+
+// зарезервированные ники (админы, торговые марки), к ним добавляются
+// Options.ReservedNicknames
+var defaultReservedNicknames = []string{"admin", "moderator", "server", "system", "guesswho"}
+
+// reservedNicknameSet собирает ники для сравнения без учета регистра
+func reservedNicknameSet(extra []string) map[string]struct{} {
+	names := make(map[string]struct{}, len(defaultReservedNicknames)+len(extra))
+	for _, name := range append(defaultReservedNicknames, extra...) {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			names[name] = struct{}{}
+		}
+	}
+	return names
+}
+
+// isReservedNickname - набор ников не меняется после NewServer, поэтому без мьютекса
+func (s *Server) isReservedNickname(nickname string) bool {
+	_, reserved := s.reservedNicknames[strings.ToLower(strings.TrimSpace(nickname))]
+	return reserved
+}
+
+// validateNickname проверяет длину и символы ника: буквы, цифры, пробел, '_' и '-',
+// без пробелов по краям
+func validateNickname(nickname string) error {
+	length := utf8.RuneCountInString(nickname)
+	if length < minNicknameLength || length > maxNicknameLength {
+		return protocolError(ErrorCodeInvalidNickname, "ERROR: nickname must be %d to %d characters long", minNicknameLength, maxNicknameLength)
+	}
+
+	if strings.TrimSpace(nickname) != nickname {
+		return protocolError(ErrorCodeInvalidNickname, "ERROR: nickname can't start or end with a space")
+	}
+
+	for _, c := range nickname {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != ' ' && c != '_' && c != '-' {
+			return protocolError(ErrorCodeInvalidNickname, "ERROR: nickname may contain only letters, digits, spaces, '_' and '-', got %q", c)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,170 @@
+package guesswho
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Options - настройки сервера. Нулевое значение поля - не "по умолчанию", а
+// именно ноль (например, 0 у лимитов трафика отключает их), поэтому начинать
+// нужно с DefaultOptions и менять только нужное
+type Options struct {
+	// лимиты трафика
+	ConnBandwidthCap      int64 // байт в секунду на соединение, 0 - без лимита
+	LobbyBandwidthCap     int64 // байт в секунду на лобби, сверх него придерживаем действия в лобби; 0 - без лимита
+	MaxBandwidthThrottles int   // сколько раз подряд троттлим входящий трафик соединения сверх ConnBandwidthCap до отключения
+
+	// лимиты входящих сообщений
+	MessageRate        float64 // сообщений в секунду на соединение в среднем
+	MessageBurst       int     // сколько сообщений можно прислать пачкой
+	MaxRateLimitedMsgs int     // сколько сообщений подряд отбрасываем до отключения
+	MaxMessageSize     int64   // жесткий лимит кадра, больше него соединение закрывается с кодом 1009
+
+	// нагрузка
+	SoftPlayerCapacity     int // при таком числе онлайн игроков создание лобби ограничивается
+	CapacityUpdateInterval time.Duration
+
+	AdminToken        string   // bearer токен admin API, пустой отключает admin API
+	ReservedNicknames []string // в дополнение к встроенным, сравниваются без учета регистра
+	Meta              Meta     // начальный брендинг для /meta, дальше меняется через admin API
+
+	// соединение
+	ConnectionQualityInterval time.Duration // как часто пингуем клиента и шлем ConnectionQuality
+	PongWait                  time.Duration // сколько ждем сообщений или pong, больше ConnectionQualityInterval
+	WriteWait                 time.Duration // сколько может длиться одна запись клиенту
+	MaxBatchMessages          int           // сколько сообщений влезает в один кадр протокола 4, <= 1 отключает пакеты
+	MaxBatchBytes             int           // после такого размера кадра сообщения в пакет больше не добавляются
+	ReplayBufferSize          int           // сколько последних сообщений сессии храним для переподключения, 0 - не храним
+	RejoinGracePeriod         time.Duration // сколько держим место отключившегося игрока, 0 - не держим
+
+	// быстрый поиск
+	MatchmakingTimeout     time.Duration
+	RegionPreferenceWindow time.Duration // после этого подбираем соперника из любого региона
+
+	// время жизни
+	LobbyEmptyTTL       time.Duration // пустое лобби
+	LobbyIdleTTL        time.Duration // лобби без активности
+	JanitorInterval     time.Duration
+	IdempotencyKeyTTL   time.Duration
+	InviteTokenTTL      time.Duration
+	PlayerInvitationTTL time.Duration
+
+	AutoStartCountdown time.Duration
+	MaxLobbyPlayers    int // верхний предел настройки maxPlayers
+	AvatarsCount       int // индексы аватаров 0..AvatarsCount-1
+
+	// минимальная версия приложения, см. appversion.go; пустая отключает проверку
+	MinClientVersion  string
+	ClientDownloadURL string
+
+	// CheckOrigin проверяет Origin при подключении WebSocket и SSE (/sse и
+	// /sse/{stream}), nil пускает всех. Он же открывает CORS для
+	// /lobbies/{id}/invite, при nil CORS там закрыт
+	CheckOrigin func(r *http.Request) bool
+}
+
+// DefaultOptions возвращает настройки, с которыми сервер запускается без флагов
+func DefaultOptions() Options {
+	return Options{
+		ConnBandwidthCap:      64 * 1024,
+		LobbyBandwidthCap:     256 * 1024,
+		MaxBandwidthThrottles: 5,
+
+		MessageRate:        20,
+		MessageBurst:       40,
+		MaxRateLimitedMsgs: 20,
+		MaxMessageSize:     16 * 1024,
+
+		SoftPlayerCapacity:     500,
+		CapacityUpdateInterval: 30 * time.Second,
+
+		Meta: Meta{ServerName: "GuessWhoServer"},
+
+		ConnectionQualityInterval: 5 * time.Second,
+		PongWait:                  15 * time.Second,
+		WriteWait:                 10 * time.Second,
+		MaxBatchMessages:          32,
+		MaxBatchBytes:             64 * 1024,
+		ReplayBufferSize:          64,
+		RejoinGracePeriod:         60 * time.Second,
+
+		MatchmakingTimeout:     2 * time.Minute,
+		RegionPreferenceWindow: 15 * time.Second,
+
+		LobbyEmptyTTL:       time.Minute,
+		LobbyIdleTTL:        30 * time.Minute,
+		JanitorInterval:     30 * time.Second,
+		IdempotencyKeyTTL:   10 * time.Minute,
+		InviteTokenTTL:      24 * time.Hour,
+		PlayerInvitationTTL: time.Minute,
+
+		AutoStartCountdown: 5 * time.Second,
+		MaxLobbyPlayers:    8,
+		AvatarsCount:       16,
+	}
+}
+
+func (o Options) validate() error {
+	if o.PongWait <= o.ConnectionQualityInterval {
+		return fmt.Errorf("ERROR: PongWait (%v) must be greater than ConnectionQualityInterval (%v)", o.PongWait, o.ConnectionQualityInterval)
+	}
+
+	for name, interval := range map[string]time.Duration{
+		"ConnectionQualityInterval": o.ConnectionQualityInterval,
+		"CapacityUpdateInterval":    o.CapacityUpdateInterval,
+		"JanitorInterval":           o.JanitorInterval,
+	} {
+		if interval <= 0 {
+			return fmt.Errorf("ERROR: %s must be positive, got %v", name, interval)
+		}
+	}
+
+	if o.MaxLobbyPlayers < 2 {
+		return fmt.Errorf("ERROR: MaxLobbyPlayers must be at least 2, got %d", o.MaxLobbyPlayers)
+	}
+
+	if _, ok := parseAppVersion(o.MinClientVersion); o.MinClientVersion != "" && !ok {
+		return fmt.Errorf("ERROR: MinClientVersion %q is not a version like 1.4.2", o.MinClientVersion)
+	}
+
+	return nil
+}
+
+// LoadReservedNicknames читает ники из файла, по одному на строку, для
+// Options.ReservedNicknames
+func LoadReservedNicknames(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var names []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if name := strings.TrimSpace(scanner.Text()); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names, scanner.Err()
+}
+
+// LoadMeta читает брендинг развертывания из JSON файла для Options.Meta
+func LoadMeta(path string) (Meta, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Meta{}, err
+	}
+
+	var m Meta
+	if err := json.Unmarshal(data, &m); err != nil {
+		return Meta{}, err
+	}
+	return m, nil
+}
@@ -0,0 +1,292 @@
+package guesswho
+
+// входящие payload'ы по типам сообщений, форма JSON совпадает с прежним
+// общим Payload, поэтому клиентов менять не нужно. Исходящие сообщения
+// по-прежнему собираются из Payload. omitempty во входящих типах ничего не
+// меняет при разборе, он помечает необязательные поля в схеме (см. schema.go)
+
+// PlayerProfile - ник и аватар, которые игрок сообщает о себе
+type PlayerProfile struct {
+	Nickname  string `json:"nickname"`
+	AvatarIdx int    `json:"avatarIdx,omitempty"`
+}
+
+// PlayerRef - ссылка на другого игрока
+type PlayerRef struct {
+	ID       string `json:"id,omitempty"`
+	Nickname string `json:"nickname,omitempty"`
+}
+
+type LobbyRef struct {
+	ID string `json:"id"`
+}
+
+// LobbyInfo - параметры лобби, которые выбирает хост при создании
+type LobbyInfo struct {
+	ID       string `json:"id,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Region   string `json:"region,omitempty"`
+	IsPublic bool   `json:"isPublic,omitempty"`
+}
+
+type InvitationRef struct {
+	ID string `json:"id"`
+}
+
+type HelloRequest struct {
+	ProtocolVersion int `json:"protocolVersion"`
+}
+
+// TimeSyncRequest - время отправки по часам клиента, миллисекунды Unix
+type TimeSyncRequest struct {
+	ClientTime int64 `json:"clientTime"`
+}
+
+type CreateLobbyRequest struct {
+	Player   *PlayerProfile `json:"player"`
+	Lobby    *LobbyInfo     `json:"lobby,omitempty"`
+	Settings *LobbySettings `json:"settings,omitempty"`
+}
+
+type JoinLobbyRequest struct {
+	Player      *PlayerProfile `json:"player"`
+	Lobby       *LobbyRef      `json:"lobby"`
+	InviteToken string         `json:"inviteToken,omitempty"`
+}
+
+// KickPlayerRequest - Ban запрещает игроку возвращаться в лобби, пока оно
+// живо. Бан держится на ID игрока, а ID живет, пока жива его сессия: после
+// возврата по resume token игрок остается забаненным, но новое подключение без
+// resume token получает новый ID, и сервер его не узнает. Постоянной личности
+// игрока у сервера нет, а бан по адресу задел бы всех за тем же NAT
+type KickPlayerRequest struct {
+	Player *PlayerRef `json:"player"`
+	Ban    bool       `json:"ban,omitempty"`
+}
+
+type TransferHostRequest struct {
+	Player *PlayerRef `json:"player"`
+}
+
+type UpdateLobbySettingsRequest struct {
+	Settings *LobbySettings `json:"settings"`
+}
+
+// FindMatchRequest - все поля необязательные, из settings берется только
+// gameMode, из lobby - только region
+type FindMatchRequest struct {
+	Player   *PlayerProfile `json:"player,omitempty"`
+	Settings *LobbySettings `json:"settings,omitempty"`
+	Lobby    *LobbyInfo     `json:"lobby,omitempty"`
+}
+
+type QueueForLobbyRequest struct {
+	Player *PlayerProfile `json:"player,omitempty"`
+	Lobby  *LobbyRef      `json:"lobby"`
+}
+
+type LeaveLobbyQueueRequest struct {
+	Lobby *LobbyRef `json:"lobby"`
+}
+
+type JoinAsSpectatorRequest struct {
+	Player *PlayerProfile `json:"player,omitempty"`
+	Lobby  *LobbyRef      `json:"lobby"`
+}
+
+type RejoinLobbyRequest struct {
+	ResumeToken string `json:"resumeToken"`
+}
+
+type InvitePlayerRequest struct {
+	Player *PlayerRef `json:"player"`
+}
+
+type RespondToInvitationRequest struct {
+	Invitation *InvitationRef `json:"invitation"`
+}
+
+func requiredError(field string) error {
+	return protocolError(ErrorCodeInvalidRequest, "ERROR: %s is required", field)
+}
+
+func (p *PlayerProfile) validate() error {
+	if err := validateNickname(p.Nickname); err != nil {
+		return err
+	}
+
+	// верхняя граница зависит от настроек сервера, ее проверяет applyProfile
+	if p.AvatarIdx < 0 {
+		return protocolError(ErrorCodeInvalidAvatar, "ERROR: avatar index can't be negative, got %d", p.AvatarIdx)
+	}
+
+	return nil
+}
+
+// validateProfile проверяет необязательный профиль, nil допустим
+func validateProfile(profile *PlayerProfile) error {
+	if profile == nil {
+		return nil
+	}
+	return profile.validate()
+}
+
+// validateLobbyRef проверяет ссылку на существующее лобби, ее код должен
+// быть в формате кодов лобби
+func validateLobbyRef(lobby *LobbyRef) error {
+	if lobby == nil || lobby.ID == "" {
+		return requiredError("lobby id")
+	}
+	return validateLobbyCode(lobby.ID)
+}
+
+func (l *LobbyInfo) validate() error {
+	if l.ID != "" {
+		if err := validateLobbyCode(l.ID); err != nil {
+			return err
+		}
+	}
+	return validateLobbyMetadata(l.Name, l.Region)
+}
+
+func (r *TimeSyncRequest) validate() error {
+	if r.ClientTime == 0 {
+		return requiredError("clientTime")
+	}
+	return nil
+}
+
+func (r *HelloRequest) validate() error {
+	if r.ProtocolVersion == 0 {
+		return requiredError("protocolVersion")
+	}
+	return nil
+}
+
+func (r *CreateLobbyRequest) validate() error {
+	if r.Player == nil {
+		return requiredError("player")
+	}
+	if err := r.Player.validate(); err != nil {
+		return err
+	}
+
+	// настройки проверяет createLobby, здесь только формат кода, имени и региона
+	if r.Lobby != nil {
+		return r.Lobby.validate()
+	}
+	return nil
+}
+
+func (r *JoinLobbyRequest) validate() error {
+	if r.Player == nil {
+		return requiredError("player")
+	}
+	if err := r.Player.validate(); err != nil {
+		return err
+	}
+	return validateLobbyRef(r.Lobby)
+}
+
+func (r *KickPlayerRequest) validate() error {
+	if r.Player == nil || r.Player.ID == "" {
+		return requiredError("player id")
+	}
+	return nil
+}
+
+func (r *TransferHostRequest) validate() error {
+	if r.Player == nil || r.Player.ID == "" {
+		return requiredError("player id")
+	}
+	return nil
+}
+
+func (r *UpdateLobbySettingsRequest) validate() error {
+	if r.Settings == nil {
+		return requiredError("settings")
+	}
+	return nil
+}
+
+func (r *FindMatchRequest) validate() error {
+	if err := validateProfile(r.Player); err != nil {
+		return err
+	}
+
+	if r.Settings != nil && r.Settings.GameMode != "" && r.Settings.GameMode != GameModeClassic {
+		return protocolError(ErrorCodeInvalidSettings, "ERROR: unknown game mode %s", r.Settings.GameMode)
+	}
+
+	if r.Lobby != nil {
+		return validateLobbyMetadata("", r.Lobby.Region)
+	}
+	return nil
+}
+
+func (r *QueueForLobbyRequest) validate() error {
+	if err := validateProfile(r.Player); err != nil {
+		return err
+	}
+	return validateLobbyRef(r.Lobby)
+}
+
+func (r *LeaveLobbyQueueRequest) validate() error {
+	return validateLobbyRef(r.Lobby)
+}
+
+func (r *JoinAsSpectatorRequest) validate() error {
+	if err := validateProfile(r.Player); err != nil {
+		return err
+	}
+	return validateLobbyRef(r.Lobby)
+}
+
+func (r *RejoinLobbyRequest) validate() error {
+	if r.ResumeToken == "" {
+		return requiredError("resumeToken")
+	}
+	return nil
+}
+
+func (r *InvitePlayerRequest) validate() error {
+	if r.Player == nil || (r.Player.ID == "" && r.Player.Nickname == "") {
+		return requiredError("player id or nickname")
+	}
+	return nil
+}
+
+func (r *RespondToInvitationRequest) validate() error {
+	if r.Invitation == nil || r.Invitation.ID == "" {
+		return requiredError("invitation id")
+	}
+	return nil
+}
+
+// applyProfile проверяет ник и аватар из запроса по настройкам сервера и
+// записывает их игроку, nil - игрок ничего о себе не сообщил. Профиль меняется
+// только вне лобби и под p.mu: игрока в это время может посадить к себе хаб
+// matchmaker'а или очереди, и после claimLobby хаб читает профиль без блокировок
+func (p *Player) applyProfile(profile *PlayerProfile) error {
+	if profile == nil {
+		return nil
+	}
+
+	if avatarsCount := p.server.opts.AvatarsCount; profile.AvatarIdx >= avatarsCount {
+		return protocolError(ErrorCodeInvalidAvatar, "ERROR: avatar index must be between 0 and %d, got %d", avatarsCount-1, profile.AvatarIdx)
+	}
+
+	if p.server.isReservedNickname(profile.Nickname) {
+		return protocolError(ErrorCodeNicknameReserved, "ERROR: nickname %s is reserved", profile.Nickname)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.curLobby != nil {
+		return protocolError(ErrorCodeAlreadyInLobby, "ERROR: player is already in a lobby")
+	}
+	p.AvatarIdx = profile.AvatarIdx
+	p.Nickname = profile.Nickname
+	return nil
+}
@@ -0,0 +1,86 @@
+package guesswho
+
+import (
+	"encoding/json"
+	"log"
+	"strconv"
+)
+
+// версии протокола:
+//
+//	1 - ошибки вида {type, message}, без Ack
+//	2 - конверт ошибок с кодом и requestType, Ack на запросы с requestId
+//	3 - LobbyStateDelta вместо полного лобби в событиях
+//	4 - несколько сообщений в одном кадре JSON массивом, см. batch.go
+const (
+	protocolVersion    = 4
+	minProtocolVersion = 1
+
+	// клиенты без версии получают поведение версии 2, дельты нужно запросить явно
+	defaultProtocolVersion = 2
+	deltaProtocolVersion   = 3
+	batchProtocolVersion   = 4
+)
+
+func supportedProtocolVersion(version int) bool {
+	return version >= minProtocolVersion && version <= protocolVersion
+}
+
+// parseProtocolVersion разбирает версию из query параметра protocolVersion,
+// клиенты без версии получают defaultProtocolVersion
+func parseProtocolVersion(raw string) (int, error) {
+	if raw == "" {
+		return defaultProtocolVersion, nil
+	}
+
+	version, err := strconv.Atoi(raw)
+	if err != nil || !supportedProtocolVersion(version) {
+		return 0, unsupportedProtocolError(raw)
+	}
+	return version, nil
+}
+
+func unsupportedProtocolError(version any) *ProtocolError {
+	return protocolError(ErrorCodeUnsupportedProtocol, "ERROR: protocol version %v is not supported, supported versions are %d to %d", version, minProtocolVersion, protocolVersion)
+}
+
+// negotiated возвращает согласованную версию протокола игрока, 0 - клиент
+// объявил несовместимую версию и может прислать только новый Hello
+func (p *Player) negotiated() int {
+	return int(p.protocolVersion.Load())
+}
+
+func handleHello(player *Player, payloadJson json.RawMessage) {
+	var request HelloRequest
+	if !decodeRequest(player, payloadJson, &request) {
+		return
+	}
+
+	if !supportedProtocolVersion(request.ProtocolVersion) {
+		player.protocolVersion.Store(0)
+		player.sendErr(unsupportedProtocolError(request.ProtocolVersion))
+		return
+	}
+
+	player.protocolVersion.Store(int32(request.ProtocolVersion))
+
+	log.Printf("INFO: player %s speaks protocol version %d", player.ID, request.ProtocolVersion)
+
+	player.send(generateMsg(WsMessageTypeHello, Payload{ProtocolVersion: request.ProtocolVersion}))
+}
+
+// errorMsg собирает ошибку в том виде, который понимает версия протокола игрока
+func (p *Player) errorMsg(request inboundRequest, code ErrorCode, message string) []byte {
+	if p.negotiated() == 1 {
+		bytes, _ := json.Marshal(struct {
+			Type    WsMessageType `json:"type"`
+			Message string        `json:"message"`
+		}{
+			Type:    WsMessageTypeError,
+			Message: message,
+		})
+		return bytes
+	}
+
+	return errorResponse(request, code, message, localizeError(p.locale, code))
+}
@@ -0,0 +1,149 @@
+package guesswho
+
+import (
+	"errors"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+type ConnectionQuality struct {
+	PlayerID         string `json:"playerId"`
+	RttMs            int64  `json:"rttMs"`
+	MissedHeartbeats int    `json:"missedHeartbeats"`
+	SendQueueDepth   int    `json:"sendQueueDepth"`
+}
+
+// состояние пингов соединения
+type heartbeat struct {
+	mu               sync.Mutex
+	rtt              time.Duration
+	srtt             time.Duration // сглаженный RTT, как в TCP: новое измерение весит 1/8
+	awaitingPong     bool
+	missedHeartbeats int
+}
+
+// extendReadDeadline продлевает дедлайн чтения, если клиент молчит дольше
+// Options.PongWait, ReadMessage вернет ошибку и игрок будет отключен
+func extendReadDeadline(player *Player) {
+	if err := player.Conn.SetReadDeadline(time.Now().Add(player.server.opts.PongWait)); err != nil {
+		log.Printf("WARNING: can't set read deadline for player %s, error: %v", player.id(), err)
+	}
+}
+
+// isIdleTimeout - ReadMessage вернул ошибку из-за дедлайна чтения: клиент
+// Options.PongWait не присылал ни сообщений, ни pong
+func isIdleTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// announceTimeout сообщает лобби, что игрок отключен за молчание, дальше
+// disconnect обрабатывает его как обычный обрыв соединения
+func announceTimeout(player *Player) {
+	log.Printf("INFO: player %s timed out after %v of silence", player.id(), player.server.opts.PongWait)
+
+	lobby := player.lobby()
+	if lobby == nil {
+		return
+	}
+
+	lobby.do(func() {
+		if player.lobby() != lobby {
+			return
+		}
+		lobby.logEvent(LobbyEventPlayerTimedOut, player, "")
+		lobby.broadcast(lobby.snapshot(WsMessageTypePlayerTimedOut, player))
+	})
+}
+
+// onPong вызывается из читающей горутины, в payload лежит время отправки пинга
+func (h *heartbeat) onPong(appData string) {
+	sentAt, err := strconv.ParseInt(appData, 10, 64)
+	if err != nil {
+		log.Printf("WARNING: got pong with malformed payload: %s", appData)
+		return
+	}
+
+	h.mu.Lock()
+	h.rtt = time.Since(time.Unix(0, sentAt))
+	if h.srtt == 0 {
+		h.srtt = h.rtt
+	} else {
+		h.srtt += (h.rtt - h.srtt) / 8
+	}
+	h.awaitingPong = false
+	h.missedHeartbeats = 0
+	h.mu.Unlock()
+}
+
+// smoothedRTT - сглаженный RTT соединения, 0 - еще не было ни одного pong
+func (h *heartbeat) smoothedRTT() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.srtt
+}
+
+func (h *heartbeat) beforePing() {
+	h.mu.Lock()
+	if h.awaitingPong {
+		h.missedHeartbeats++
+	}
+	h.awaitingPong = true
+	h.mu.Unlock()
+}
+
+func (h *heartbeat) quality(player *Player) *ConnectionQuality {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return &ConnectionQuality{
+		PlayerID:         player.id(),
+		RttMs:            h.rtt.Milliseconds(),
+		MissedHeartbeats: h.missedHeartbeats,
+		SendQueueDepth:   len(player.SendChan),
+	}
+}
+
+// qualityReporter пингует клиента и рассылает ConnectionQuality ему и его лобби
+func qualityReporter(player *Player) {
+	interval := player.server.opts.ConnectionQualityInterval
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-player.Done:
+			return
+		case <-ticker.C:
+		}
+
+		msg := generateMsg(WsMessageTypeConnectionQuality, Payload{Quality: player.heartbeat.quality(player)})
+
+		recipients := []*Player{player}
+		if lobby := player.lobby(); lobby != nil {
+			lobby.do(func() {
+				if player.lobby() == lobby && !player.IsSpectator {
+					recipients = lobby.members(AudienceEveryone)
+				}
+			})
+		}
+		for _, recipient := range recipients {
+			if !recipient.sendPresence(msg) {
+				log.Printf("WARNING: presence queue of player %s is full, skipping connection quality", recipient.id())
+			}
+		}
+
+		player.heartbeat.beforePing()
+		ping := []byte(strconv.FormatInt(time.Now().UnixNano(), 10))
+		if err := player.Conn.WriteControl(websocket.PingMessage, ping, time.Now().Add(interval)); err != nil {
+			log.Printf("ERROR: can't send ping to player %s, error: %v", player.id(), err)
+			disconnect(player, closePingFailed)
+			return
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package guesswho
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+)
+
+// enqueue ставит игрока в очередь ожидания лобби и возвращает его позицию
+func (l *Lobby) enqueue(player *Player) int {
+	for i, queued := range l.queue {
+		if queued == player {
+			return i + 1
+		}
+	}
+	l.queue = append(l.queue, player)
+	return len(l.queue)
+}
+
+func (l *Lobby) dequeue(player *Player) bool {
+	for i, queued := range l.queue {
+		if queued == player {
+			l.queue = append(l.queue[:i], l.queue[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// promoteQueued сажает первых ожидающих на освободившиеся места и
+// рассылает оставшимся их новые позиции. Закрытое лобби никого не сажает:
+// очередь ждет, пока хост его откроет
+func (l *Lobby) promoteQueued() {
+	for !l.IsLocked && len(l.queue) > 0 && len(l.Players) < l.Settings.MaxPlayers {
+		next := l.queue[0]
+		l.queue = l.queue[1:]
+
+		if isDisconnected(next) || next.lobby() != nil {
+			continue
+		}
+
+		if err := l.join(next, false); err != nil {
+			next.send(next.errorMsg(inboundRequest{Type: WsMessageTypeQueueForLobby}, errorCode(err), err.Error()))
+			continue
+		}
+
+		log.Printf("INFO: player %s joined lobby %s from the queue", next.ID, l.ID)
+
+		l.broadcastSnapshot(WsMessageTypeLobbyJoined, next)
+	}
+
+	l.maybeAutoStart()
+
+	queue := l.queue[:0]
+	for _, queued := range l.queue {
+		if !isDisconnected(queued) {
+			queue = append(queue, queued)
+		}
+	}
+	l.queue = queue
+
+	for i, queued := range queue {
+		queued.send(generateMsg(WsMessageTypeLobbyQueuePosition, Payload{Lobby: &Lobby{ID: l.ID}, QueuePosition: i + 1}))
+	}
+}
+
+func handleQueueForLobby(player *Player, payloadJson json.RawMessage) {
+	var request QueueForLobbyRequest
+	if !decodeRequest(player, payloadJson, &request) {
+		return
+	}
+
+	if player.lobby() != nil {
+		player.sendError(ErrorCodeAlreadyInLobby, "ERROR: player is already in a lobby")
+		return
+	}
+
+	if err := player.applyProfile(request.Player); err != nil {
+		player.sendErr(err)
+		return
+	}
+
+	err := player.server.withLobby(request.Lobby.ID, func(lobby *Lobby) error {
+		position := lobby.enqueue(player)
+
+		log.Printf("INFO: player %s queued for lobby %s at position %d", player.ID, lobby.ID, position)
+
+		player.send(generateMsg(WsMessageTypeLobbyQueuePosition, Payload{Lobby: &Lobby{ID: lobby.ID}, QueuePosition: position}))
+
+		// вдруг место уже свободно
+		lobby.promoteQueued()
+		return nil
+	})
+	if err != nil {
+		player.sendErr(err)
+	}
+}
+
+func handleLeaveLobbyQueue(player *Player, payloadJson json.RawMessage) {
+	var request LeaveLobbyQueueRequest
+	if !decodeRequest(player, payloadJson, &request) {
+		return
+	}
+
+	queued := false
+	player.server.withLobby(request.Lobby.ID, func(lobby *Lobby) error {
+		if queued = lobby.dequeue(player); queued {
+			lobby.promoteQueued()
+		}
+		return nil
+	})
+	if !queued {
+		player.sendError(ErrorCodeNotQueued, fmt.Sprintf("ERROR: player is not queued for lobby %s", request.Lobby.ID))
+	}
+}
@@ -0,0 +1,71 @@
+package guesswho
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// queueFor встает в очередь лобби code и ждет свою позицию
+func (c *testClient) queueFor(code, nickname string) int {
+	c.t.Helper()
+
+	c.send(WsMessageTypeQueueForLobby, fmt.Sprintf(`{"player":{"nickname":%q},"lobby":{"id":%q}}`, nickname, code))
+	return c.expect(WsMessageTypeLobbyQueuePosition).decode(c.t).QueuePosition
+}
+
+// закрытое лобби держит очередь, а открытие сажает первого ожидающего
+func TestQueueWaitsWhileLocked(t *testing.T) {
+	server := newTestServer(t, DefaultOptions())
+
+	host := server.dial(t, "")
+	host.createLobby("QUE001", 2)
+
+	second := server.dial(t, "")
+	second.joinLobby("QUE001", "second")
+	second.expect(WsMessageTypeLobbyJoined)
+
+	queued := server.dial(t, "")
+	if position := queued.queueFor("QUE001", "queued"); position != 1 {
+		t.Fatalf("got queue position %d, want 1", position)
+	}
+
+	host.send(WsMessageTypeLockLobby, "")
+	host.expect(WsMessageTypeLobbyLockChanged)
+
+	second.send(WsMessageTypePlayerQuit, "")
+	host.expect(WsMessageTypePlayerLeft)
+
+	// место свободно, но лобби закрыто: игрок остается первым в очереди
+	if position := queued.expect(WsMessageTypeLobbyQueuePosition).decode(t).QueuePosition; position != 1 {
+		t.Errorf("got queue position %d, want 1", position)
+	}
+	queued.expectNone(WsMessageTypeError, 200*time.Millisecond)
+
+	host.send(WsMessageTypeUnlockLobby, "")
+	joined := queued.expect(WsMessageTypeLobbyJoined).decode(t)
+	if joined.Lobby == nil || len(joined.Lobby.Players) != 2 {
+		t.Fatalf("got lobby %+v, want 2 players", joined.Lobby)
+	}
+}
+
+// рост maxPlayers сразу сажает ожидающих
+func TestQueuePromotedOnSettingsUpdate(t *testing.T) {
+	server := newTestServer(t, DefaultOptions())
+
+	host := server.dial(t, "")
+	host.createLobby("QUE002", 2)
+
+	second := server.dial(t, "")
+	second.joinLobby("QUE002", "second")
+	second.expect(WsMessageTypeLobbyJoined)
+
+	queued := server.dial(t, "")
+	queued.queueFor("QUE002", "queued")
+
+	host.send(WsMessageTypeUpdateLobbySettings, `{"settings":{"turnTimerSeconds":60,"gameMode":"Classic","characterPack":"default","maxPlayers":3}}`)
+	joined := queued.expect(WsMessageTypeLobbyJoined).decode(t)
+	if joined.Lobby == nil || len(joined.Lobby.Players) != 3 {
+		t.Fatalf("got lobby %+v, want 3 players", joined.Lobby)
+	}
+}
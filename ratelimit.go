@@ -0,0 +1,40 @@
+package guesswho
+
+import "time"
+
+// tokenBucket - лимитер входящих сообщений соединения, трогает его только
+// читающая горутина игрока, поэтому без мьютекса
+type tokenBucket struct {
+	tokens     float64
+	lastRefill time.Time
+	limited    int // сколько сообщений подряд отброшено
+}
+
+// allow списывает токен за сообщение, false - сообщение надо отбросить
+func (b *tokenBucket) allow(rate float64, burst int) bool {
+	now := time.Now()
+	if b.lastRefill.IsZero() {
+		b.tokens = float64(burst)
+	} else {
+		b.tokens += now.Sub(b.lastRefill).Seconds() * rate
+		if b.tokens > float64(burst) {
+			b.tokens = float64(burst)
+		}
+	}
+	b.lastRefill = now
+
+	if b.tokens < 1 {
+		b.limited++
+		return false
+	}
+
+	b.tokens--
+	b.limited = 0
+	return true
+}
+
+// abusive - клиент шлет сверх лимита больше maxLimited сообщений подряд, его
+// пора отключать
+func (b *tokenBucket) abusive(maxLimited int) bool {
+	return b.limited > maxLimited
+}
@@ -0,0 +1,230 @@
+package guesswho
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"time"
+)
+
+func newResumeToken() string {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		log.Printf("ERROR: can't generate resume token, error: %v", err)
+		return ""
+	}
+	return hex.EncodeToString(raw)
+}
+
+// onDisconnect решает, что делать с лобби игрока после обрыва соединения:
+// игрокам держим место Options.RejoinGracePeriod, остальных сразу убираем
+func onDisconnect(player *Player) {
+	// место и ID уже переходят новому соединению, см. resumeSession
+	if player.replaced.Load() {
+		return
+	}
+
+	gracePeriod := player.server.opts.RejoinGracePeriod
+	lobby := player.lobby()
+	if lobby == nil || gracePeriod <= 0 {
+		leaveLobby(player)
+		forgetSession(player)
+		return
+	}
+
+	held := false
+	lobby.do(func() {
+		if player.lobby() != lobby {
+			return
+		}
+		if player.IsSpectator {
+			lobby.leave(player)
+			return
+		}
+		held = true
+
+		log.Printf("INFO: holding seat of player %s in lobby %s for %v", player.ID, lobby.ID, gracePeriod)
+		lobby.logEvent(LobbyEventPlayerDisconnected, player, "")
+
+		lobby.broadcast(generateMsg(WsMessageTypePlayerDisconnected, Payload{Lobby: lobby, Player: player}))
+
+		// хоста передаем сразу, не дожидаясь конца удержания места
+		if player.IsHost {
+			player.IsHost = false
+			lobby.reassignHost()
+		}
+		// пока место придержано, лобби не готово, отсчет автостарта отменяется
+		lobby.maybeAutoStart()
+	})
+	if !held {
+		forgetSession(player)
+		return
+	}
+
+	player.mu.Lock()
+	player.graceTimer = time.AfterFunc(gracePeriod, func() {
+		log.Printf("INFO: grace period of player %s expired", player.ID)
+		leaveLobby(player)
+		forgetSession(player)
+	})
+	player.mu.Unlock()
+}
+
+func forgetSession(player *Player) {
+	s := player.server
+	s.mu.Lock()
+	if s.Sessions[player.resumeToken] == player {
+		delete(s.Sessions, player.resumeToken)
+	}
+	if s.Players[player.ID] == player {
+		delete(s.Players, player.ID)
+	}
+	s.mu.Unlock()
+}
+
+// handleRejoinLobby сажает новое соединение на удерживаемое место старого
+func handleRejoinLobby(player *Player, payloadJson json.RawMessage) {
+	var request RejoinLobbyRequest
+	if !decodeRequest(player, payloadJson, &request) {
+		return
+	}
+
+	if player.lobby() != nil {
+		player.sendError(ErrorCodeAlreadyInLobby, "ERROR: player is already in a lobby")
+		return
+	}
+
+	// соединение уже получает свои номера сообщений, нумерацию старого не берем
+	lobby, _, err := resumeSession(player, request.ResumeToken)
+	if err != nil {
+		player.sendErr(err)
+		return
+	}
+	if lobby == nil {
+		return
+	}
+
+	lobby.do(func() {
+		lobby.broadcastSnapshot(WsMessageTypePlayerReconnected, player)
+		lobby.maybeAutoStart()
+	})
+}
+
+// resumeSession привязывает новое соединение к удерживаемому игроку: тот же ID,
+// то же лобби и место в нем. Если старое соединение еще живо, игрок открыл
+// второе, и сессия переходит к новому, а старое закрывается с SessionReplaced.
+// Снимок лобби для пересинхронизации рассылает вызывающий, лобби может быть nil,
+// если живой игрок ни в каком лобби не был. old - прежнее соединение сессии
+func resumeSession(player *Player, resumeToken string) (*Lobby, *Player, error) {
+	s := player.server
+	s.mu.Lock()
+	old, exists := s.Sessions[resumeToken]
+	s.mu.Unlock()
+
+	if resumeToken == "" || !exists || old == player {
+		return nil, nil, protocolError(ErrorCodeInvalidResumeToken, "ERROR: resume token is invalid or expired")
+	}
+
+	replaced := !isDisconnected(old)
+	if replaced {
+		replaceSession(old)
+	}
+
+	// ID и токен новое соединение берет у старого в том же событии хаба, где
+	// занимает его место, чтобы лобби не увидело игрока с чужим ID. Пишем их под
+	// Player.mu: писатель, пинги и matchmaker читают ID через id()
+	adopt := func() {
+		s.mu.Lock()
+		delete(s.Players, player.ID)
+		delete(s.Sessions, player.resumeToken)
+		player.mu.Lock()
+		player.ID = old.ID
+		player.resumeToken = old.resumeToken
+		player.mu.Unlock()
+		s.Players[player.ID] = player
+		s.Sessions[player.resumeToken] = player
+		s.mu.Unlock()
+	}
+
+	lobby := old.lobby()
+	seated := false
+	switch {
+	case lobby != nil:
+		lobby.do(func() {
+			if seated = lobby.replacePlayer(old, player); seated {
+				adopt()
+				lobby.logEvent(LobbyEventPlayerReconnected, player, "")
+			}
+		})
+	case replaced:
+		adopt()
+		seated = true
+	}
+	if !seated {
+		// старое соединение уже закрыто заменой, и его onDisconnect сессию не
+		// убирает, поэтому убираем ее здесь
+		if replaced {
+			forgetSession(old)
+		}
+		return nil, nil, protocolError(ErrorCodeSeatNotReserved, "ERROR: seat is no longer reserved")
+	}
+
+	old.mu.Lock()
+	if old.graceTimer != nil {
+		old.graceTimer.Stop()
+	}
+	old.mu.Unlock()
+
+	if lobby == nil {
+		log.Printf("INFO: player %s moved to a new connection", player.ID)
+		return nil, old, nil
+	}
+
+	log.Printf("INFO: player %s rejoined lobby %s", player.ID, lobby.ID)
+
+	return lobby, old, nil
+}
+
+// replaceSession закрывает живое соединение игрока, который подключился заново.
+// onDisconnect для него ничего не делает: место в лобби забирает новое соединение
+func replaceSession(old *Player) {
+	old.replaced.Store(true)
+	disconnect(old, closeSessionReplaced)
+}
+
+// replacePlayer передает место старого соединения новому, вызывается из хаба
+func (l *Lobby) replacePlayer(old, player *Player) bool {
+	for i, lobbyPlayer := range l.Players {
+		if lobbyPlayer == old {
+			player.adoptProfile(old)
+			player.IsHost = old.IsHost
+			player.IsReady = old.IsReady
+			l.Players[i] = player
+			old.setLobby(nil)
+			player.setLobby(l)
+			return true
+		}
+	}
+
+	// зрителей при обрыве сразу убираем, здесь они бывают только при замене живой сессии
+	for i, spectator := range l.Spectators {
+		if spectator == old {
+			player.adoptProfile(old)
+			player.IsSpectator = true
+			l.Spectators[i] = player
+			old.setLobby(nil)
+			player.setLobby(l)
+			return true
+		}
+	}
+	return false
+}
+
+// adoptProfile берет ник и аватар старого соединения, под p.mu, как applyProfile
+func (p *Player) adoptProfile(old *Player) {
+	p.mu.Lock()
+	p.Nickname = old.Nickname
+	p.AvatarIdx = old.AvatarIdx
+	p.mu.Unlock()
+}
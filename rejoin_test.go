@@ -0,0 +1,139 @@
+package guesswho
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// dropConnection обрывает соединение клиента и ждет, пока лобби придержит его
+// место
+func (s *testServer) dropConnection(t *testing.T, c *testClient, lobbyID string) {
+	t.Helper()
+
+	s.mu.Lock()
+	player := s.Players[c.id]
+	lobby := s.Lobbies[lobbyID]
+	s.mu.Unlock()
+
+	c.conn.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		held := false
+		lobby.do(func() { held = isDisconnected(player) && lobby.findPlayer(player.ID) == player && !player.IsHost })
+		if held {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("seat of player %s is not held", c.id)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// единственный хост обрывает связь и возвращается: хостом он и остается
+func TestResumeRestoresSoloHost(t *testing.T) {
+	server := newTestServer(t, DefaultOptions())
+
+	host := server.dial(t, "")
+	host.createLobby("REJ001", 4)
+	server.dropConnection(t, host, "REJ001")
+
+	resumed := server.dial(t, "resumeToken="+url.QueryEscape(host.resumeToken))
+	if changed := resumed.expect(WsMessageTypeHostChanged).decode(t); changed.Player == nil || changed.Player.ID != host.id {
+		t.Fatalf("got new host %+v, want %s", changed.Player, host.id)
+	}
+
+	resumed.send(WsMessageTypeUpdateLobbySettings, `{"settings":{"turnTimerSeconds":90,"gameMode":"Classic","characterPack":"default","maxPlayers":4}}`)
+	resumed.expect(WsMessageTypeLobbySettingsUpdated)
+
+	guest := server.dial(t, "")
+	guest.joinLobby("REJ001", "guest")
+	joined := guest.expect(WsMessageTypeLobbyJoined).decode(t)
+	if joined.Lobby == nil || len(joined.Lobby.Players) != 2 || !joined.Lobby.Players[0].IsHost {
+		t.Errorf("got lobby %+v, want the returned host first", joined.Lobby)
+	}
+}
+
+// лобби закрылось, пока новое соединение забирало живую сессию: старое
+// соединение уже закрыто заменой, и его сессия не должна остаться на сервере
+func TestResumeIntoClosedLobbyForgetsOldSession(t *testing.T) {
+	server := newTestServer(t, DefaultOptions())
+
+	old, _ := server.newPlayer(context.Background(), nil, url.Values{})
+	player, _ := server.newPlayer(context.Background(), nil, url.Values{})
+	server.mu.Lock()
+	for _, p := range []*Player{old, player} {
+		server.Players[p.ID] = p
+		server.Sessions[p.resumeToken] = p
+	}
+	server.mu.Unlock()
+
+	host, _ := server.newPlayer(context.Background(), nil, url.Values{})
+	lobby, err := server.createLobby(host, LobbyOptions{Code: "REJ002"})
+	if err != nil {
+		t.Fatalf("createLobby: %v", err)
+	}
+	lobby.do(func() { lobby.close(LobbyCloseReasonEmpty) })
+	// старое соединение еще считает себя в лобби, как если бы оно закрылось
+	// уже после того, как resumeSession его прочитал
+	old.setLobby(lobby)
+
+	if _, _, err := resumeSession(player, old.resumeToken); errorCode(err) != ErrorCodeSeatNotReserved {
+		t.Fatalf("got error %v, want %s", err, ErrorCodeSeatNotReserved)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if server.Players[old.ID] == old || server.Sessions[old.resumeToken] == old {
+		t.Error("session of the replaced connection is left on the server")
+	}
+	if server.Players[player.ID] != player {
+		t.Error("new connection lost its own session")
+	}
+}
+
+// пока место гостя придержано, хост не может начать игру, даже если гость
+// успел отметиться готовым
+func TestStartGameWaitsForHeldSeat(t *testing.T) {
+	server := newTestServer(t, DefaultOptions())
+
+	host := server.dial(t, "")
+	host.createLobby("RDY001", 4)
+	guest := server.dial(t, "")
+	guest.joinLobby("RDY001", "guest")
+	guest.expect(WsMessageTypeLobbyJoined)
+
+	guest.send(WsMessageTypePlayerReady, "")
+	host.expect(WsMessageTypePlayerReadyChanged)
+
+	server.dropConnection(t, guest, "RDY001")
+
+	host.send(WsMessageTypeStartGame, "")
+	host.expectError(ErrorCodePlayersNotReady)
+}
+
+// обрыв связи отменяет отсчет автостарта, возвращение на место запускает его снова
+func TestAutoStartPausedWhileSeatHeld(t *testing.T) {
+	server := newTestServer(t, DefaultOptions())
+
+	host := server.dial(t, "")
+	host.createLobby("RDY002", 2)
+	host.send(WsMessageTypeUpdateLobbySettings, `{"settings":{"turnTimerSeconds":90,"gameMode":"Classic","characterPack":"default","maxPlayers":2,"autoStart":true}}`)
+	host.expect(WsMessageTypeLobbySettingsUpdated)
+
+	guest := server.dial(t, "")
+	guest.joinLobby("RDY002", "guest")
+	guest.expect(WsMessageTypeLobbyJoined)
+	guest.send(WsMessageTypePlayerReady, "")
+	host.expect(WsMessageTypeAutoStartCountdown)
+
+	server.dropConnection(t, guest, "RDY002")
+	host.expect(WsMessageTypeAutoStartCancelled)
+
+	resumed := server.dial(t, "resumeToken="+url.QueryEscape(guest.resumeToken))
+	resumed.expect(WsMessageTypeAutoStartCountdown)
+	host.expect(WsMessageTypeAutoStartCountdown)
+}
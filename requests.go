@@ -0,0 +1,73 @@
+package guesswho
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+)
+
+// inboundRequest - сообщение клиента, которое сейчас обрабатывается. Если клиент
+// передал requestId, на запрос придет ровно один ответ с этим id: Error или Ack
+type inboundRequest struct {
+	Type     WsMessageType
+	ID       string
+	failed   bool
+	response []byte // ответ запросившему, запоминается по idempotencyKey
+}
+
+// AckResponse подтверждает успешно обработанный запрос, сами изменения
+// состояния клиент к этому моменту уже получил обычными сообщениями
+type AckResponse struct {
+	Seq         uint64        `json:"seq,omitempty"` // проставляет writer
+	Type        WsMessageType `json:"type"`
+	RequestType WsMessageType `json:"requestType"`
+	RequestID   string        `json:"requestId"`
+}
+
+// respond отправляет игроку ответ на текущий запрос
+func (p *Player) respond(msg []byte) {
+	p.request.response = msg
+	p.send(msg)
+}
+
+func (r inboundRequest) ack(player *Player) {
+	if r.ID == "" || r.failed || player.negotiated() < 2 {
+		return
+	}
+
+	response := AckResponse{
+		Type:        WsMessageTypeAck,
+		RequestType: r.Type,
+		RequestID:   r.ID,
+	}
+
+	bytes, err := json.Marshal(response)
+	if err != nil {
+		log.Printf("ERROR: failed marshal JSON: ack, error: %v", err)
+		return
+	}
+	player.send(bytes)
+}
+
+// payloadRequest - типизированный payload входящего сообщения, см. payloads.go
+type payloadRequest interface {
+	validate() error
+}
+
+// decodeRequest разбирает payload в request и проверяет обязательные поля,
+// при ошибке сам отвечает клиенту и возвращает false
+func decodeRequest(player *Player, payloadJson json.RawMessage, request payloadRequest) bool {
+	if len(payloadJson) > 0 {
+		if err := json.Unmarshal(payloadJson, request); err != nil {
+			log.Printf("ERROR: can't unmarshal %s msg, error: %v", player.request.Type, err)
+			player.sendError(ErrorCodeInvalidRequest, fmt.Sprintf("ERROR: can't parse %s payload", player.request.Type))
+			return false
+		}
+	}
+
+	if err := request.validate(); err != nil {
+		player.sendErr(err)
+		return false
+	}
+	return true
+}
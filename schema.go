@@ -0,0 +1,262 @@
+package guesswho
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JSON Schema (draft 2020-12) протокола для генерации типов на клиентах.
+// Поля берутся из json тегов структур, поэтому схема меняется вместе с ними,
+// а новый тип сообщения нужно только добавить в clientMessages или serverMessages.
+// Из этих же реестров генерируются типы TypeScript для веб-клиента
+
+//go:generate go run ./tools/tsgen -out web/protocol.ts -client client/messages.go
+
+// clientMessages - payload каждого сообщения клиента, nil - payload не нужен
+var clientMessages = map[WsMessageType]any{
+	WsMessageTypeHello:               HelloRequest{},
+	WsMessageTypeCreateLobby:         CreateLobbyRequest{},
+	WsMessageTypeJoinLobby:           JoinLobbyRequest{},
+	WsMessageTypePlayerQuit:          nil,
+	WsMessageTypePlayerReady:         nil,
+	WsMessageTypePlayerUnready:       nil,
+	WsMessageTypeStartGame:           nil,
+	WsMessageTypeKickPlayer:          KickPlayerRequest{},
+	WsMessageTypeUpdateLobbySettings: UpdateLobbySettingsRequest{},
+	WsMessageTypeFindMatch:           FindMatchRequest{},
+	WsMessageTypeCancelFindMatch:     nil,
+	WsMessageTypeJoinAsSpectator:     JoinAsSpectatorRequest{},
+	WsMessageTypeTransferHost:        TransferHostRequest{},
+	WsMessageTypeLockLobby:           nil,
+	WsMessageTypeUnlockLobby:         nil,
+	WsMessageTypeQueueForLobby:       QueueForLobbyRequest{},
+	WsMessageTypeLeaveLobbyQueue:     LeaveLobbyQueueRequest{},
+	WsMessageTypeRejoinLobby:         RejoinLobbyRequest{},
+	WsMessageTypeGetLobbyEvents:      nil,
+	WsMessageTypeInvitePlayer:        InvitePlayerRequest{},
+	WsMessageTypeAcceptInvitation:    RespondToInvitationRequest{},
+	WsMessageTypeDeclineInvitation:   RespondToInvitationRequest{},
+	WsMessageTypeRequestSync:         nil,
+	WsMessageTypeTimeSync:            TimeSyncRequest{},
+}
+
+// serverMessages - payload каждого сообщения сервера, почти все собираются из Payload
+var serverMessages = map[WsMessageType]any{
+	WsMessageTypeHello:                Payload{},
+	WsMessageTypeConnected:            Payload{},
+	WsMessageTypeLobbyCreated:         Payload{},
+	WsMessageTypeLobbyJoined:          Payload{},
+	WsMessageTypeCapacityUpdated:      Payload{},
+	WsMessageTypeConnectionQuality:    Payload{},
+	WsMessageTypePlayerReadyChanged:   Payload{},
+	WsMessageTypeGameStarted:          Payload{},
+	WsMessageTypeKickedFromLobby:      Payload{},
+	WsMessageTypePlayerKicked:         Payload{},
+	WsMessageTypeLobbySettingsUpdated: Payload{},
+	WsMessageTypeMatchmakingQueued:    Payload{},
+	WsMessageTypeMatchmakingCancelled: Payload{},
+	WsMessageTypeMatchmakingTimedOut:  Payload{},
+	WsMessageTypeMatchFound:           Payload{},
+	WsMessageTypeSpectatorJoined:      Payload{},
+	WsMessageTypeHostChanged:          Payload{},
+	WsMessageTypeLobbyClosed:          Payload{},
+	WsMessageTypeLobbyLockChanged:     Payload{},
+	WsMessageTypePlayerLeft:           Payload{},
+	WsMessageTypeLobbyQueuePosition:   Payload{},
+	WsMessageTypePlayerDisconnected:   Payload{},
+	WsMessageTypePlayerTimedOut:       Payload{},
+	WsMessageTypePlayerReconnected:    Payload{},
+	WsMessageTypeSpectatorsChanged:    Payload{},
+	WsMessageTypeLobbyEvents:          Payload{},
+	WsMessageTypeAutoStartCountdown:   Payload{},
+	WsMessageTypeAutoStartCancelled:   Payload{},
+	WsMessageTypeInvitationReceived:   Payload{},
+	WsMessageTypeInvitationUpdated:    Payload{},
+	WsMessageTypeSyncState:            Payload{},
+	WsMessageTypeLobbyStateDelta:      LobbyStateDelta{},
+	WsMessageTypeTimeSync:             Payload{},
+	WsMessageTypeUpgradeRequired:      Payload{},
+}
+
+// presenceMessages - фоновые сообщения сервера, они идут без номера seq
+var presenceMessages = map[WsMessageType]struct{}{
+	WsMessageTypeCapacityUpdated:   {},
+	WsMessageTypeConnectionQuality: {},
+}
+
+// serverEnvelopes - сообщения сервера без payload, их поля лежат прямо в конверте
+var serverEnvelopes = map[WsMessageType]any{
+	WsMessageTypeError: ErrorResponse{},
+	WsMessageTypeAck:   AckResponse{},
+}
+
+type ProtocolSchema struct {
+	Schema          string                       `json:"$schema"`
+	Title           string                       `json:"title"`
+	ProtocolVersion int                          `json:"protocolVersion"`
+	ClientMessages  map[WsMessageType]JSONSchema `json:"clientMessages"`
+	ServerMessages  map[WsMessageType]JSONSchema `json:"serverMessages"`
+	Defs            map[string]JSONSchema        `json:"$defs"`
+}
+
+type JSONSchema map[string]any
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemaBuilder собирает схемы типов, именованные структуры уходят в $defs
+type schemaBuilder struct {
+	defs map[string]JSONSchema
+}
+
+func (b *schemaBuilder) schemaOf(t reflect.Type) JSONSchema {
+	switch t {
+	case timeType:
+		return JSONSchema{"type": "string", "format": "date-time"}
+	case rawMessageType:
+		return JSONSchema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return b.schemaOf(t.Elem())
+	case reflect.Bool:
+		return JSONSchema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return JSONSchema{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return JSONSchema{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return JSONSchema{"type": "number"}
+	case reflect.String:
+		return JSONSchema{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return JSONSchema{"type": "array", "items": b.schemaOf(t.Elem())}
+	case reflect.Map:
+		return JSONSchema{"type": "object", "additionalProperties": b.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		if _, exists := b.defs[t.Name()]; !exists {
+			b.defs[t.Name()] = nil // рекурсивные ссылки, например Lobby -> Player
+			b.defs[t.Name()] = b.structSchema(t)
+		}
+		return JSONSchema{"$ref": "#/$defs/" + t.Name()}
+	}
+
+	return JSONSchema{}
+}
+
+// structSchema описывает поля структуры по json тегам, поля без omitempty
+// считаются обязательными
+func (b *schemaBuilder) structSchema(t reflect.Type) JSONSchema {
+	properties := JSONSchema{}
+	required := []string{}
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = b.schemaOf(field.Type)
+		if !strings.Contains(options, "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	schema := JSONSchema{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// messageSchema - конверт {type, requestId, payload} сообщения msgType
+func (b *schemaBuilder) messageSchema(msgType WsMessageType, payload any, fromClient bool) JSONSchema {
+	properties := JSONSchema{"type": JSONSchema{"const": msgType}}
+	required := []string{"type"}
+
+	if fromClient {
+		properties["requestId"] = JSONSchema{"type": "string"}
+		if _, idempotent := idempotentMessages[msgType]; idempotent {
+			properties["idempotencyKey"] = JSONSchema{"type": "string", "maxLength": maxIdempotencyKeyLength}
+		}
+	} else if _, presence := presenceMessages[msgType]; !presence {
+		properties["seq"] = JSONSchema{"type": "integer", "minimum": 1}
+	}
+	if payload != nil {
+		payloadType := reflect.TypeOf(payload)
+		properties["payload"] = b.schemaOf(payloadType)
+
+		// payload клиента, в котором все поля необязательные, можно не присылать
+		if _, hasRequired := b.defs[payloadType.Name()]["required"]; hasRequired || !fromClient {
+			required = append(required, "payload")
+		}
+	}
+
+	return JSONSchema{"type": "object", "properties": properties, "required": required}
+}
+
+func buildProtocolSchema() ProtocolSchema {
+	builder := &schemaBuilder{defs: make(map[string]JSONSchema)}
+
+	schema := ProtocolSchema{
+		Schema:          "https://json-schema.org/draft/2020-12/schema",
+		Title:           "GuessWho WebSocket protocol",
+		ProtocolVersion: protocolVersion,
+		ClientMessages:  make(map[WsMessageType]JSONSchema),
+		ServerMessages:  make(map[WsMessageType]JSONSchema),
+	}
+
+	for msgType, payload := range clientMessages {
+		schema.ClientMessages[msgType] = builder.messageSchema(msgType, payload, true)
+	}
+	for msgType, payload := range serverMessages {
+		schema.ServerMessages[msgType] = builder.messageSchema(msgType, payload, false)
+	}
+	for msgType, envelope := range serverEnvelopes {
+		schema.ServerMessages[msgType] = builder.schemaOf(reflect.TypeOf(envelope))
+	}
+
+	schema.Defs = builder.defs
+	return schema
+}
+
+// схема зависит только от типов, поэтому собирается один раз
+var protocolSchemaJSON = sync.OnceValue(func() []byte {
+	bytes, err := json.MarshalIndent(buildProtocolSchema(), "", "  ")
+	if err != nil {
+		log.Printf("ERROR: failed marshal JSON: protocol schema, error: %v", err)
+		return []byte(`{}`)
+	}
+	return bytes
+})
+
+func handleSchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/schema+json")
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Write(protocolSchemaJSON())
+}
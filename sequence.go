@@ -0,0 +1,132 @@
+package guesswho
+
+import (
+	"bytes"
+	"log"
+	"strconv"
+	"sync"
+)
+
+// Номера сообщений: writer проставляет каждому сообщению из SendChan поле seq,
+// номера идут подряд с 1 в пределах сессии и продолжаются, когда клиент
+// возвращается по resume token при подключении. Пропуск номера значит, что
+// сообщение потерялось, и клиенту нужен RequestSync. Фоновые сообщения из
+// PresenceChan номеров не получают, их можно терять. Connected тоже идет без
+// номера: он начинает поток соединения и сообщает в lastSeq, на каком номере
+// сессия остановилась.
+//
+// Последние Options.ReplayBufferSize сообщений сессии хранятся, и клиент, который
+// переподключился с resumeToken и lastSeq, получает пропущенные сразу после
+// Connected. Если нужных уже нет в буфере, вместо них приходит SyncState
+
+// sequence - нумерация сообщений сессии, переходит к новому соединению вместе с ней
+type sequence struct {
+	mu     sync.Mutex
+	last   uint64
+	replay [][]byte // последние сообщения, replay[i] имеет номер last-len(replay)+1+i
+	size   int      // сколько последних сообщений держим для повтора, 0 - не держим
+}
+
+// stamp присваивает сообщению следующий номер: {"type":...} становится
+// {"seq":N,"type":...}. Сообщения уже с номером (повтор) и Connected не трогает
+func (s *sequence) stamp(msg []byte) []byte {
+	if len(msg) < 2 || msg[0] != '{' || stamped(msg) || bytes.HasPrefix(msg, connectedPrefix) {
+		return msg
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.last++
+	stampedMsg := make([]byte, 0, len(msg)+24)
+	stampedMsg = append(stampedMsg, `{"seq":`...)
+	stampedMsg = strconv.AppendUint(stampedMsg, s.last, 10)
+	stampedMsg = append(stampedMsg, ',')
+	stampedMsg = append(stampedMsg, msg[1:]...)
+
+	if s.size > 0 {
+		if len(s.replay) >= s.size {
+			s.replay = append(s.replay[:0], s.replay[len(s.replay)-s.size+1:]...)
+		}
+		s.replay = append(s.replay, stampedMsg)
+	}
+
+	return stampedMsg
+}
+
+// WsMessage без Seq начинается с type, см. generateConnectedMsg
+var connectedPrefix = []byte(`{"type":"` + WsMessageTypeConnected + `"`)
+
+func stamped(msg []byte) bool {
+	return bytes.HasPrefix(msg, []byte(`{"seq":`))
+}
+
+func (s *sequence) lastSeq() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.last
+}
+
+// since возвращает сообщения с номерами после after, ok == false - часть из
+// них уже вытеснена из буфера
+func (s *sequence) since(after uint64) (messages [][]byte, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if after >= s.last {
+		return nil, true
+	}
+	missed := s.last - after
+	if missed > uint64(len(s.replay)) {
+		return nil, false
+	}
+
+	return append([][]byte(nil), s.replay[uint64(len(s.replay))-missed:]...), true
+}
+
+// parseLastSeq разбирает query параметр lastSeq, -1 - клиент его не прислал
+func parseLastSeq(raw string) (int64, error) {
+	if raw == "" {
+		return -1, nil
+	}
+
+	lastSeq, err := strconv.ParseUint(raw, 10, 63)
+	if err != nil {
+		return -1, protocolError(ErrorCodeInvalidRequest, "ERROR: lastSeq must be a non-negative integer, got %s", raw)
+	}
+	return int64(lastSeq), nil
+}
+
+// resumeStream досылает на новом соединении то, что клиент пропустил после
+// lastSeq, нумерация к этому моменту уже перешла от old (см. resumeSession).
+// Вызывается до запуска writer'а, сразу после Connected
+func resumeStream(player, old *Player, lastSeq int64) {
+	if lastSeq >= 0 && uint64(lastSeq) < player.sequence.lastSeq() {
+		missed, ok := player.sequence.since(uint64(lastSeq))
+		if ok {
+			log.Printf("INFO: replaying %d messages to player %s", len(missed), player.ID)
+			for _, message := range missed {
+				player.send(message)
+			}
+		} else {
+			log.Printf("INFO: messages after %d of player %s are no longer buffered, sending SyncState", lastSeq, player.ID)
+			sendSyncState(player)
+		}
+	}
+
+	// то, что пришло старому соединению после обрыва, writer уже не отправил;
+	// если writer еще работает (сессию заменили), он допишет это сам
+	select {
+	case <-old.writerDone:
+	default:
+		return
+	}
+	for {
+		select {
+		case message := <-old.SendChan:
+			player.send(message)
+		default:
+			return
+		}
+	}
+}
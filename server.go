@@ -0,0 +1,1054 @@
+// Package guesswho - сервер лобби GuessWho, который можно встроить в свой
+// HTTP сервер. Server реализует http.Handler со всеми маршрутами: /ws, /sse,
+// /lobbies, /ping и остальными.
+//
+//	srv, err := guesswho.NewServer(guesswho.DefaultOptions())
+//	httpServer := &http.Server{Addr: ":8080", Handler: srv}
+//	go httpServer.ListenAndServe()
+//	...
+//	srv.Shutdown(ctx)
+//	httpServer.Shutdown(ctx)
+//
+// Готовый бинарник с настройками через флаги - cmd/guesswhoserver
+package guesswho
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// геймплей; IsHost, IsReady и IsSpectator игрока в лобби меняет только хаб
+// лобби, см. hub.go
+type Player struct {
+	ID           string             `json:"id,omitempty"`
+	Nickname     string             `json:"nickname,omitempty"`
+	AvatarIdx    int                `json:"avatarIdx,omitempty"`
+	IsHost       bool               `json:"isHost,omitempty"`
+	IsReady      bool               `json:"isReady"`
+	IsSpectator  bool               `json:"isSpectator,omitempty"`
+	Conn         Transport          `json:"-"` // *websocket.Conn или SSE поток, см. sse.go
+	SendChan     chan []byte        `json:"-"` // сообщения о лобби и игре, доставляются первыми
+	PresenceChan chan []byte        `json:"-"` // фоновые сообщения (качество связи, нагрузка), можно терять
+	Bandwidth    Bandwidth          `json:"-"`
+	Done         <-chan struct{}    `json:"-"` // ctx.Done(), закрывается при отключении
+	ctx          context.Context    `json:"-"` // живет, пока живо соединение, см. disconnect
+	cancel       context.CancelFunc `json:"-"`
+	unwatch      func()             `json:"-"` // отписывает от отмены запроса и остановки сервера
+	server       *Server            `json:"-"`
+	closeOnce    sync.Once          `json:"-"`
+	closeReason  closeReason        `json:"-"` // записывается до закрытия Done
+	writerDone   chan struct{}      `json:"-"` // writer отправил close frame и вышел
+	sequence     *sequence          `json:"-"` // номера сообщений сессии, см. sequence.go
+	request      inboundRequest     `json:"-"` // запрос, который сейчас обрабатывает читающая горутина
+	throttles    int                `json:"-"` // сколько входящих кадров подряд превысили лимит трафика
+
+	protocolVersion atomic.Int32 `json:"-"` // согласованная версия протокола, см. protocol.go
+	limiter         tokenBucket  `json:"-"`
+	encoding        Encoding     `json:"-"` // формат кадров, выбирается при подключении
+	locale          string       `json:"-"` // язык сообщений для пользователя, выбирается при подключении
+	heartbeat       heartbeat    `json:"-"`
+	mu              sync.Mutex   `json:"-"`
+	curLobby        *Lobby       `json:"-"`
+
+	resumeToken string      `json:"-"` // секрет, отдается только самому игроку в Connected
+	graceTimer  *time.Timer `json:"-"`
+	replaced    atomic.Bool `json:"-"` // сессию забрало новое соединение с тем же resume token
+}
+
+func (p *Player) lobby() *Lobby {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.curLobby
+}
+
+// id читает ID игрока не из его читающей горутины и не из хаба его лобби:
+// ID меняется, когда соединение продолжает чужую сессию, см. resumeSession
+func (p *Player) id() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.ID
+}
+
+// profile копирует ID, ник и аватар игрока для сообщений, которые собираются
+// не в хабе его лобби: флаги живого игрока в это время меняет хаб
+func (p *Player) profile() *Player {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return &Player{ID: p.ID, Nickname: p.Nickname, AvatarIdx: p.AvatarIdx}
+}
+
+// nickname читает ник игрока не из хаба его лобби, см. applyProfile
+func (p *Player) nickname() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.Nickname
+}
+
+func (p *Player) setLobby(lobby *Lobby) {
+	p.mu.Lock()
+	p.curLobby = lobby
+	p.mu.Unlock()
+}
+
+// claimLobby записывает игроку лобби, только если он еще ни в каком не состоит:
+// так два хаба не посадят его к себе одновременно
+func (p *Player) claimLobby(lobby *Lobby) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.curLobby != nil {
+		return false
+	}
+	p.curLobby = lobby
+	return true
+}
+
+type Lobby struct {
+	ID         string        `json:"id,omitempty"` // 6 символов
+	Name       string        `json:"name,omitempty"`
+	Region     string        `json:"region,omitempty"`
+	Players    []*Player     `json:"players,omitempty"`
+	Spectators []*Player     `json:"spectators,omitempty"`
+	IsPublic   bool          `json:"isPublic"`
+	IsLocked   bool          `json:"isLocked"`
+	InGame     bool          `json:"inGame"`
+	Settings   LobbySettings `json:"settings"`
+	Revision   uint64        `json:"revision"` // последняя разосланная дельта, см. delta.go
+	Bandwidth  Bandwidth     `json:"-"`
+
+	// события хаба, см. hub.go; все поля ниже, кроме lastActivity, трогает только хаб
+	inbox   chan func()   `json:"-"`
+	closed  chan struct{} `json:"-"` // хаб остановлен
+	stopped bool          `json:"-"` // лобби закрыто, хаб выходит после текущего события
+
+	lastActivity atomic.Int64        `json:"-"` // UnixNano, см. touch
+	banned       map[string]struct{} `json:"-"` // ID игроков, живет вместе с лобби, см. KickPlayerRequest
+	queue        []*Player           `json:"-"` // ждут свободного места
+	events       []LobbyEvent        `json:"-"`
+
+	published map[string]json.RawMessage `json:"-"` // состояние на момент Revision
+
+	autoStartTimer *time.Timer `json:"-"`
+
+	server *Server `json:"-"`
+}
+
+// Payload - payload исходящих сообщений, входящие разбираются в типы из payloads.go
+type Payload struct {
+	Lobby  *Lobby  `json:"lobby,omitempty"`  // Используем указатель
+	Player *Player `json:"player,omitempty"` // Используем указатель
+
+	Capacity *Capacity          `json:"capacity,omitempty"`
+	Quality  *ConnectionQuality `json:"quality,omitempty"`
+	Settings *LobbySettings     `json:"settings,omitempty"`
+
+	QueuePosition int    `json:"queuePosition,omitempty"`
+	ResumeToken   string `json:"resumeToken,omitempty"`
+	LastSeq       uint64 `json:"lastSeq,omitempty"` // номер последнего сообщения сессии, только в Connected
+
+	Spectators  *SpectatorsInfo  `json:"spectators,omitempty"`
+	CloseReason LobbyCloseReason `json:"closeReason,omitempty"`
+	Events      []LobbyEvent     `json:"events,omitempty"`
+
+	CountdownSeconds int `json:"countdownSeconds,omitempty"`
+
+	Invitation *Invitation `json:"invitation,omitempty"`
+
+	ProtocolVersion int    `json:"protocolVersion,omitempty"`
+	Locale          string `json:"locale,omitempty"` // выбранная локаль, только в Connected
+
+	Sync *SyncState `json:"sync,omitempty"`
+
+	Upgrade *UpgradeRequired `json:"upgrade,omitempty"`
+
+	TimeSync *TimeSync `json:"timeSync,omitempty"`
+}
+
+// сервер
+type Server struct {
+	Lobbies   map[string]*Lobby  `json:"-"`
+	Players   map[string]*Player `json:"-"`
+	Sessions  map[string]*Player `json:"-"` // по resume token
+	Bandwidth Bandwidth          `json:"-"`
+	mu        sync.Mutex         `json:"-"`
+
+	opts     Options
+	mux      *http.ServeMux
+	upgrader websocket.Upgrader
+
+	ctx         context.Context // отменяется в Shutdown, см. shutdown.go
+	stop        context.CancelFunc
+	connections sync.WaitGroup // открытые соединения игроков, и WebSocket, и SSE
+
+	matchmaker        *Matchmaker
+	invites           inviteStore
+	invitations       invitationStore
+	idempotency       idempotencyStore
+	meta              metaStore
+	sseStreams        sseRegistry
+	reservedNicknames map[string]struct{}
+}
+
+// NewServer создает сервер с настройками opts и запускает его фоновые горутины:
+// подбор соперников, рассылку нагрузки и уборку лобби. Они работают до Shutdown
+func NewServer(opts Options) (*Server, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		Lobbies:  make(map[string]*Lobby),
+		Players:  make(map[string]*Player),
+		Sessions: make(map[string]*Player),
+
+		opts: opts,
+		mux:  http.NewServeMux(),
+
+		invites:           inviteStore{tokens: make(map[string]invite)},
+		invitations:       invitationStore{byID: make(map[string]*Invitation)},
+		idempotency:       idempotencyStore{results: make(map[string]idempotentResult)},
+		meta:              metaStore{Meta: opts.Meta},
+		sseStreams:        sseRegistry{byID: make(map[string]*sseStream)},
+		reservedNicknames: reservedNicknameSet(opts.ReservedNicknames),
+	}
+
+	s.upgrader.CheckOrigin = opts.CheckOrigin
+	if s.upgrader.CheckOrigin == nil {
+		s.upgrader.CheckOrigin = func(r *http.Request) bool { return true }
+	}
+
+	s.ctx, s.stop = context.WithCancel(context.Background())
+	s.matchmaker = newMatchmaker(s)
+
+	s.mux.HandleFunc("/ping", s.handlePing)
+	s.mux.HandleFunc("/bandwidth", s.handleBandwidth)
+	s.mux.HandleFunc("/meta", s.handleMeta)
+	s.mux.HandleFunc("/schema", handleSchema)
+	s.mux.HandleFunc("/lobbies", s.handleLobbies)
+	s.mux.HandleFunc("/lobbies/{id}", s.handleLobby)
+	s.mux.HandleFunc("/lobbies/{id}/invite", s.handleInvite)
+	s.mux.HandleFunc("/ws", s.handleWebSocket)
+	s.mux.HandleFunc("/sse", s.handleSSE)
+	s.mux.HandleFunc("/sse/{stream}", s.handleSSEMessage)
+
+	go s.capacityNotifier(s.ctx)
+	go s.matchmaker.run(s.ctx)
+	go s.janitor(s.ctx)
+
+	return s, nil
+}
+
+// ServeHTTP обслуживает маршруты сервера, пути - от корня; чтобы смонтировать
+// сервер под префиксом, оберните его в http.StripPrefix
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// вебсокет сообщения
+type WsMessageType string
+
+const (
+	// общие типы
+	WsMessageTypeUnknown  WsMessageType = "Unknown"
+	WsMessageTypeError    WsMessageType = "Error"
+	WsMessageTypeAck      WsMessageType = "Ack"
+	WsMessageTypeHello    WsMessageType = "Hello"    // клиент объявляет версию протокола, сервер подтверждает
+	WsMessageTypeTimeSync WsMessageType = "TimeSync" // клиент сверяет часы с сервером, см. timesync.go
+
+	// client -> server types
+	WsMessageTypeCreateLobby WsMessageType = "CreateLobby"
+	WsMessageTypeJoinLobby   WsMessageType = "JoinLobby"
+	WsMessageTypePlayerQuit  WsMessageType = "PlayerQuit"
+
+	WsMessageTypePlayerReady   WsMessageType = "PlayerReady"
+	WsMessageTypePlayerUnready WsMessageType = "PlayerUnready"
+	WsMessageTypeStartGame     WsMessageType = "StartGame"
+	WsMessageTypeKickPlayer    WsMessageType = "KickPlayer"
+
+	WsMessageTypeUpdateLobbySettings WsMessageType = "UpdateLobbySettings"
+
+	WsMessageTypeFindMatch       WsMessageType = "FindMatch"
+	WsMessageTypeCancelFindMatch WsMessageType = "CancelFindMatch"
+
+	WsMessageTypeJoinAsSpectator WsMessageType = "JoinAsSpectator"
+	WsMessageTypeTransferHost    WsMessageType = "TransferHost"
+	WsMessageTypeLockLobby       WsMessageType = "LockLobby"
+	WsMessageTypeUnlockLobby     WsMessageType = "UnlockLobby"
+
+	WsMessageTypeQueueForLobby   WsMessageType = "QueueForLobby"
+	WsMessageTypeLeaveLobbyQueue WsMessageType = "LeaveLobbyQueue"
+	WsMessageTypeRejoinLobby     WsMessageType = "RejoinLobby"
+	WsMessageTypeGetLobbyEvents  WsMessageType = "GetLobbyEvents"
+
+	WsMessageTypeInvitePlayer      WsMessageType = "InvitePlayer"
+	WsMessageTypeAcceptInvitation  WsMessageType = "AcceptInvitation"
+	WsMessageTypeDeclineInvitation WsMessageType = "DeclineInvitation"
+
+	WsMessageTypeRequestSync WsMessageType = "RequestSync"
+
+	// server -> client types
+	WsMessageTypeConnected    WsMessageType = "Connected"
+	WsMessageTypeLobbyCreated WsMessageType = "LobbyCreated"
+	WsMessageTypeLobbyJoined  WsMessageType = "LobbyJoined"
+
+	WsMessageTypeCapacityUpdated   WsMessageType = "CapacityUpdated"
+	WsMessageTypeConnectionQuality WsMessageType = "ConnectionQuality"
+
+	WsMessageTypePlayerReadyChanged WsMessageType = "PlayerReadyChanged"
+	WsMessageTypeGameStarted        WsMessageType = "GameStarted"
+	WsMessageTypeKickedFromLobby    WsMessageType = "KickedFromLobby"
+	WsMessageTypePlayerKicked       WsMessageType = "PlayerKicked"
+
+	WsMessageTypeLobbySettingsUpdated WsMessageType = "LobbySettingsUpdated"
+
+	WsMessageTypeMatchmakingQueued    WsMessageType = "MatchmakingQueued"
+	WsMessageTypeMatchmakingCancelled WsMessageType = "MatchmakingCancelled"
+	WsMessageTypeMatchmakingTimedOut  WsMessageType = "MatchmakingTimedOut"
+	WsMessageTypeMatchFound           WsMessageType = "MatchFound"
+
+	WsMessageTypeSpectatorJoined WsMessageType = "SpectatorJoined"
+	WsMessageTypeHostChanged     WsMessageType = "HostChanged"
+	WsMessageTypeLobbyClosed     WsMessageType = "LobbyClosed"
+
+	WsMessageTypeLobbyLockChanged WsMessageType = "LobbyLockChanged"
+	WsMessageTypePlayerLeft       WsMessageType = "PlayerLeft"
+
+	WsMessageTypeLobbyQueuePosition WsMessageType = "LobbyQueuePosition"
+	WsMessageTypePlayerDisconnected WsMessageType = "PlayerDisconnected"
+	WsMessageTypePlayerTimedOut     WsMessageType = "PlayerTimedOut"
+	WsMessageTypePlayerReconnected  WsMessageType = "PlayerReconnected"
+	WsMessageTypeSpectatorsChanged  WsMessageType = "SpectatorsChanged"
+	WsMessageTypeLobbyEvents        WsMessageType = "LobbyEvents"
+
+	WsMessageTypeAutoStartCountdown WsMessageType = "AutoStartCountdown"
+	WsMessageTypeAutoStartCancelled WsMessageType = "AutoStartCancelled"
+
+	WsMessageTypeInvitationReceived WsMessageType = "InvitationReceived"
+	WsMessageTypeInvitationUpdated  WsMessageType = "InvitationUpdated"
+
+	WsMessageTypeSyncState WsMessageType = "SyncState"
+
+	WsMessageTypeLobbyStateDelta WsMessageType = "LobbyStateDelta"
+
+	WsMessageTypeUpgradeRequired WsMessageType = "UpgradeRequired"
+)
+
+type WsMessage struct {
+	Seq       uint64        `json:"seq,omitempty"` // номер сообщения сервера, проставляет writer, см. sequence.go
+	Type      WsMessageType `json:"type"`
+	RequestID string        `json:"requestId,omitempty"` // необязательный id запроса от клиента
+
+	IdempotencyKey string          `json:"idempotencyKey,omitempty"` // повтор запроса, см. idempotency.go
+	Payload        json.RawMessage `json:"payload"`
+}
+
+// параметры создания лобби
+type LobbyOptions struct {
+	Code     string // пустой - сгенерировать
+	Name     string
+	Region   string
+	IsPublic bool
+	Settings *LobbySettings // nil - настройки по умолчанию
+}
+
+func (s *Server) createLobby(player *Player, options LobbyOptions) (*Lobby, error) {
+	if options.Code != "" {
+		if err := validateLobbyCode(options.Code); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := validateLobbyMetadata(options.Name, options.Region); err != nil {
+		return nil, err
+	}
+
+	settings := defaultLobbySettings()
+	if options.Settings != nil {
+		if err := options.Settings.validate(1, s.opts.MaxLobbyPlayers); err != nil {
+			return nil, err
+		}
+		settings = *options.Settings
+	}
+
+	lobby := &Lobby{
+		Players:  []*Player{player},
+		Name:     options.Name,
+		Region:   options.Region,
+		IsPublic: options.IsPublic,
+		Settings: settings,
+
+		inbox:  make(chan func()),
+		closed: make(chan struct{}),
+		server: s,
+	}
+	lobby.touch()
+
+	if !player.claimLobby(lobby) {
+		return nil, protocolError(ErrorCodeAlreadyInLobby, "ERROR: player is already in a lobby")
+	}
+
+	s.mu.Lock()
+	if options.Code == "" {
+		lobbyID, err := s.generateLobbyCode()
+		if err != nil {
+			s.mu.Unlock()
+			lobby.discard(player)
+			return nil, err
+		}
+		lobby.ID = lobbyID
+	} else if _, exists := s.Lobbies[options.Code]; exists {
+		s.mu.Unlock()
+		lobby.discard(player)
+		return nil, protocolError(ErrorCodeLobbyCodeTaken, "ERROR: lobby code %s is already taken", options.Code)
+	} else {
+		lobby.ID = options.Code
+	}
+	s.Lobbies[lobby.ID] = lobby
+	s.mu.Unlock()
+
+	// хаб еще не запущен, поэтому участника настраиваем без событий
+	player.IsHost = true
+	player.IsReady = false
+	player.IsSpectator = false
+	lobby.logEvent(LobbyEventCreated, player, "")
+
+	go lobby.run(s.ctx)
+
+	return lobby, nil
+}
+
+// discard отменяет создание лобби, хаб которого так и не запустился: кто успел
+// взять лобби у игрока, получит от do false
+func (l *Lobby) discard(player *Player) {
+	player.setLobby(nil)
+	close(l.closed)
+}
+
+// join сажает игрока в лобби, вызывается только из хаба. invited - у игрока
+// действующее приглашение, оно пускает в закрытое лобби, но не мимо бана и
+// свободных мест
+func (l *Lobby) join(player *Player, invited bool) error {
+	if l.IsLocked && !invited {
+		return protocolError(ErrorCodeLobbyLocked, "ERROR: lobby with id %s is locked", l.ID)
+	}
+	if _, banned := l.banned[player.ID]; banned {
+		return protocolError(ErrorCodeBanned, "ERROR: player is banned from lobby with id %s", l.ID)
+	}
+	if len(l.Players) >= l.Settings.MaxPlayers {
+		return protocolError(ErrorCodeLobbyFull, "ERROR: lobby with id %s is already full", l.ID)
+	}
+	if !player.claimLobby(l) {
+		return protocolError(ErrorCodeAlreadyInLobby, "ERROR: player is already in a lobby")
+	}
+
+	player.IsHost = false
+	player.IsReady = false
+	player.IsSpectator = false
+	l.Players = append(l.Players, player)
+	l.touch()
+	l.logEvent(LobbyEventPlayerJoined, player, "")
+
+	return nil
+}
+
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	s.connections.Add(1)
+	defer s.connections.Done()
+
+	if s.shuttingDown() {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"error": "Сервер останавливается"}`))
+		return
+	}
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("ERROR: can't connect with websocket connection (can't upgrade HTTP), error: %v", err)
+		return
+	}
+
+	defer conn.Close()
+
+	player, setupErrs := s.newPlayer(r.Context(), conn, r.URL.Query())
+
+	conn.SetReadLimit(s.opts.MaxMessageSize)
+	extendReadDeadline(player)
+	conn.SetPongHandler(func(appData string) error {
+		player.heartbeat.onPong(appData)
+		extendReadDeadline(player)
+		return nil
+	})
+
+	player.connect(r.URL.Query(), setupErrs)
+
+	reason := closeByClient
+	for {
+		frameType, frame, err := conn.ReadMessage()
+		if err != nil {
+			reason = readCloseReason(err)
+			if reason == closeIdleTimeout {
+				announceTimeout(player)
+			}
+			break
+		}
+
+		if closing, stop := player.receive(frameType, frame); stop {
+			reason = closing
+			break
+		}
+	}
+
+	disconnect(player, reason)
+	<-player.writerDone
+}
+
+// newPlayer создает игрока на транспорте и разбирает параметры подключения:
+// версию протокола, формат кадров и локаль. Ошибки параметров отправляются
+// клиенту после Connected. Контекст игрока несет значения ctx запроса, а
+// отмена запроса или остановка сервера (см. shutdown.go) вызывают disconnect
+func (s *Server) newPlayer(ctx context.Context, conn Transport, query url.Values) (*Player, []error) {
+	player := &Player{
+		ID:           uuid.New().String(),
+		IsHost:       false,
+		Conn:         conn,
+		SendChan:     make(chan []byte, 256),
+		PresenceChan: make(chan []byte, presenceQueueSize),
+		writerDone:   make(chan struct{}),
+		sequence:     &sequence{size: s.opts.ReplayBufferSize},
+		server:       s,
+
+		resumeToken: newResumeToken(),
+	}
+
+	// отменяет ctx игрока только disconnect, чтобы closeReason был записан
+	// раньше, чем writer увидит Done; отмена запроса и остановка сервера
+	// приходят через disconnect
+	player.ctx, player.cancel = context.WithCancel(context.WithoutCancel(ctx))
+	player.Done = player.ctx.Done()
+	stopRequest := context.AfterFunc(ctx, func() {
+		disconnect(player, closeConnectionErr)
+	})
+	stopServer := context.AfterFunc(s.ctx, func() {
+		disconnect(player, closeServerShutdown)
+	})
+	player.unwatch = func() {
+		stopRequest()
+		stopServer()
+	}
+
+	var errs []error
+
+	// версию можно объявить сразу в query, а можно позже сообщением Hello
+	version, err := parseProtocolVersion(query.Get("protocolVersion"))
+	player.protocolVersion.Store(int32(version))
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	encoding, err := parseEncoding(query.Get("encoding"))
+	player.encoding = encoding
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	player.locale = parseLocale(query.Get("locale"))
+
+	return player, errs
+}
+
+// connect регистрирует игрока, отправляет Connected и запускает writer и
+// qualityReporter. Читать сообщения клиента дальше должен вызывающий, даже если
+// клиент отклонен за старую версию: так он дождется ответного close frame
+func (p *Player) connect(query url.Values, setupErrs []error) {
+	if upgrade := p.server.checkAppVersion(query.Get("appVersion")); upgrade != nil {
+		p.rejectOutdated(upgrade)
+		return
+	}
+
+	p.server.mu.Lock()
+	p.server.Players[p.ID] = p
+	p.server.Sessions[p.resumeToken] = p
+	p.server.mu.Unlock()
+
+	// клиент может сразу при подключении предъявить resume token и вернуться
+	// на свое место, тогда Connected уже содержит прежний ID игрока
+	var resumed *Lobby
+	var old *Player
+	var resumeErr error
+	if resumeToken := query.Get("resumeToken"); resumeToken != "" {
+		resumed, old, resumeErr = resumeSession(p, resumeToken)
+	}
+
+	lastSeq, err := parseLastSeq(query.Get("lastSeq"))
+	if err != nil {
+		setupErrs = append(setupErrs, err)
+	}
+
+	if old != nil {
+		p.sequence = old.sequence
+	}
+
+	// вернувшийся на место игрок уже в лобби, флаги хоста и готовности у него
+	// меняет хаб
+	if resumed == nil || !resumed.do(func() { p.send(generateConnectedMsg(p)) }) {
+		p.send(generateConnectedMsg(p))
+	}
+
+	if old != nil {
+		resumeStream(p, old, lastSeq)
+	}
+
+	for _, err := range setupErrs {
+		p.sendErr(err)
+	}
+
+	if resumeErr != nil {
+		p.sendErr(resumeErr)
+	} else if resumed != nil {
+		resumed.do(func() {
+			resumed.broadcastSnapshot(WsMessageTypePlayerReconnected, p)
+			resumed.maybeAutoStart()
+		})
+	}
+
+	go writer(p)
+	go qualityReporter(p)
+}
+
+// receive обрабатывает один кадр клиента, вызывается только из горутины,
+// читающей транспорт игрока. stop - соединение нужно закрыть с причиной reason
+func (p *Player) receive(frameType int, frame []byte) (reason closeReason, stop bool) {
+	// соединение уже закрывается, ждем только ответный close frame
+	if isDisconnected(p) {
+		return reason, false
+	}
+
+	opts := &p.server.opts
+
+	if wait := recordTraffic(p, len(frame), true); wait > 0 {
+		p.throttles++
+		if p.throttles > opts.MaxBandwidthThrottles {
+			log.Printf("WARNING: player %s exceeded bandwidth cap %d times in a row, disconnecting", p.ID, p.throttles)
+			return closeBandwidth, true
+		}
+		log.Printf("WARNING: player %s exceeded bandwidth cap, throttling for %v", p.ID, wait)
+		time.Sleep(wait)
+	} else {
+		p.throttles = 0
+	}
+	extendReadDeadline(p)
+
+	message, err := p.encoding.decode(frameType, frame)
+	if err != nil {
+		log.Printf("ERROR: can't decode frame of player %s, error: %v", p.ID, err)
+		p.send(p.errorMsg(inboundRequest{}, errorCode(err), err.Error()))
+		return reason, false
+	}
+
+	var msg WsMessage
+	if err := json.Unmarshal(message, &msg); err != nil {
+		log.Printf("ERROR: can't parse JSON (json.Unmarshal), error: %v", err)
+		p.send(p.errorMsg(inboundRequest{}, ErrorCodeInvalidRequest, "ERROR: message is not valid JSON"))
+		return reason, false
+	}
+
+	log.Printf("INFO: got message: %v", msg)
+
+	if lobby := p.lobby(); lobby != nil {
+		lobby.touch()
+	}
+
+	p.request = inboundRequest{Type: msg.Type, ID: msg.RequestID}
+
+	if !p.limiter.allow(opts.MessageRate, opts.MessageBurst) {
+		if p.limiter.abusive(opts.MaxRateLimitedMsgs) {
+			log.Printf("WARNING: player %s exceeded message rate %d times in a row, disconnecting", p.ID, p.limiter.limited)
+			return closeRateLimited, true
+		}
+		p.sendError(ErrorCodeRateLimited, "ERROR: too many messages, slow down")
+		return reason, false
+	}
+
+	if err := checkPayloadSize(msg); err != nil {
+		p.sendErr(err)
+		return reason, false
+	}
+
+	if p.negotiated() == 0 && msg.Type != WsMessageTypeHello {
+		p.sendError(ErrorCodeUnsupportedProtocol, "ERROR: protocol version is not negotiated, send Hello with a supported version")
+		return reason, false
+	}
+
+	if !replayIdempotent(p, msg) {
+		route(p, msg)
+		rememberIdempotent(p, msg)
+	}
+
+	p.request.ack(p)
+	return reason, false
+}
+
+// route вызывает обработчик сообщения, общий для всех транспортов
+func route(player *Player, msg WsMessage) {
+	switch msg.Type {
+	case WsMessageTypeHello:
+		handleHello(player, msg.Payload)
+	case WsMessageTypeCreateLobby:
+		handleCreateLobby(player, msg.Payload)
+	case WsMessageTypeJoinLobby:
+		handleJoinLobby(player, msg.Payload)
+	case WsMessageTypePlayerQuit:
+		handlerPlayerQuit(player, msg.Payload)
+	case WsMessageTypePlayerReady:
+		handlePlayerReady(player, true)
+	case WsMessageTypePlayerUnready:
+		handlePlayerReady(player, false)
+	case WsMessageTypeStartGame:
+		handleStartGame(player, msg.Payload)
+	case WsMessageTypeKickPlayer:
+		handleKickPlayer(player, msg.Payload)
+	case WsMessageTypeUpdateLobbySettings:
+		handleUpdateLobbySettings(player, msg.Payload)
+	case WsMessageTypeFindMatch:
+		handleFindMatch(player, msg.Payload)
+	case WsMessageTypeCancelFindMatch:
+		handleCancelFindMatch(player, msg.Payload)
+	case WsMessageTypeJoinAsSpectator:
+		handleJoinAsSpectator(player, msg.Payload)
+	case WsMessageTypeTransferHost:
+		handleTransferHost(player, msg.Payload)
+	case WsMessageTypeLockLobby:
+		handleLockLobby(player, true)
+	case WsMessageTypeUnlockLobby:
+		handleLockLobby(player, false)
+	case WsMessageTypeQueueForLobby:
+		handleQueueForLobby(player, msg.Payload)
+	case WsMessageTypeLeaveLobbyQueue:
+		handleLeaveLobbyQueue(player, msg.Payload)
+	case WsMessageTypeRejoinLobby:
+		handleRejoinLobby(player, msg.Payload)
+	case WsMessageTypeGetLobbyEvents:
+		handleGetLobbyEvents(player, msg.Payload)
+	case WsMessageTypeInvitePlayer:
+		handleInvitePlayer(player, msg.Payload)
+	case WsMessageTypeAcceptInvitation:
+		handleRespondToInvitation(player, msg.Payload, true)
+	case WsMessageTypeDeclineInvitation:
+		handleRespondToInvitation(player, msg.Payload, false)
+	case WsMessageTypeRequestSync:
+		handleRequestSync(player, msg.Payload)
+	case WsMessageTypeTimeSync:
+		handleTimeSync(player, msg.Payload)
+	default:
+		log.Printf("WARNING: unknown websocket message type: %s", msg.Type)
+		player.sendError(ErrorCodeUnknownMessageType, fmt.Sprintf("ERROR: unknown message type %s", msg.Type))
+	}
+
+}
+
+func handleCreateLobby(player *Player, payloadJson json.RawMessage) {
+	var request CreateLobbyRequest
+	if !decodeRequest(player, payloadJson, &request) {
+		return
+	}
+
+	if player.lobby() != nil {
+		player.sendError(ErrorCodeAlreadyInLobby, "ERROR: player is already in a lobby")
+		return
+	}
+
+	if player.server.capacity().LobbyCreationThrottled {
+		player.sendError(ErrorCodeServerAtCapacity, "ERROR: server is at capacity, lobby creation is throttled")
+		return
+	}
+
+	if err := player.applyProfile(request.Player); err != nil {
+		player.sendErr(err)
+		return
+	}
+
+	var options LobbyOptions
+	if request.Lobby != nil {
+		options.Code = request.Lobby.ID
+		options.Name = request.Lobby.Name
+		options.Region = request.Lobby.Region
+		options.IsPublic = request.Lobby.IsPublic
+	}
+	options.Settings = request.Settings
+
+	lobby, err := player.server.createLobby(player, options)
+	if err != nil {
+		log.Printf("ERROR: can't createLobby(), error: %v", err)
+		player.sendErr(err)
+		return
+	}
+
+	// игрока могли уже пригласить или выгнать, отвечаем в порядке событий лобби
+	lobby.do(func() {
+		player.respond(generateLobbyCreatedMsg(lobby))
+	})
+}
+
+func handleJoinLobby(player *Player, payloadJson json.RawMessage) {
+	var request JoinLobbyRequest
+	if !decodeRequest(player, payloadJson, &request) {
+		return
+	}
+
+	if player.lobby() != nil {
+		player.sendError(ErrorCodeAlreadyInLobby, "ERROR: player is already in a lobby")
+		return
+	}
+
+	if err := player.applyProfile(request.Player); err != nil {
+		player.sendErr(err)
+		return
+	}
+
+	s := player.server
+	invited := request.InviteToken != ""
+	err := s.withLobby(request.Lobby.ID, func(lobby *Lobby) error {
+		if invited {
+			if err := s.checkInvite(request.InviteToken, lobby.ID); err != nil {
+				return err
+			}
+		}
+
+		// приглашение гасим, только если по нему действительно вошли
+		if err := lobby.join(player, invited); err != nil {
+			return err
+		}
+		if invited {
+			s.consumeInvite(request.InviteToken)
+		}
+
+		player.request.response = lobby.broadcastSnapshot(WsMessageTypeLobbyJoined, player)
+		lobby.maybeAutoStart()
+		return nil
+	})
+	if err != nil {
+		player.sendErr(err)
+	}
+}
+
+// игрок уходит сознательно, поэтому место в лобби за ним не держим
+func handlerPlayerQuit(player *Player, _ json.RawMessage) {
+	leaveLobby(player)
+	disconnect(player, closePlayerQuit)
+}
+
+// disconnect - единственное место, где завершается соединение игрока; безопасен
+// для повторных вызовов из читающей горутины, writer'а, пингов и отмены
+// контекста запроса. Отмена ctx игрока останавливает qualityReporter и writer,
+// который отправляет close frame с reason и закрывает соединение (см.
+// closeConnection), а onDisconnect убирает игрока из лобби и Server.Players.
+// SendChan не закрываем: в него все еще могут писать рассылки лобби из других
+// горутин, send после отключения просто отбрасывает сообщение
+func disconnect(player *Player, reason closeReason) {
+	player.closeOnce.Do(func() {
+		log.Printf("INFO: disconnecting player %s: %s", player.id(), reason.Text)
+
+		player.closeReason = reason
+		player.cancel()
+		player.unwatch()
+
+		onDisconnect(player)
+	})
+}
+
+// send кладет сообщение в SendChan; отключенному игроку ничего не отправляем,
+// чтобы рассылка не ждала вечно очередь, которую уже никто не читает
+func (p *Player) send(msg []byte) bool {
+	select {
+	case p.SendChan <- msg:
+		return true
+	case <-p.Done:
+		return false
+	}
+}
+
+// фоновых сообщений немного держим, лишние отбрасываем: следующее все равно придет
+const presenceQueueSize = 16
+
+// sendPresence кладет фоновое сообщение в PresenceChan, не блокируясь
+func (p *Player) sendPresence(msg []byte) bool {
+	select {
+	case p.PresenceChan <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+// writer пишет сообщения игрока в соединение, фоновые сообщения из PresenceChan
+// уходят только когда в SendChan пусто, чтобы поток presence на медленном канале
+// не задерживал сообщения о лобби и игре
+func writer(player *Player) {
+	defer close(player.writerDone)
+
+	for {
+		var message []byte
+		select {
+		case <-player.Done:
+			closeConnection(player)
+			return
+		case message = <-player.SendChan:
+			message = player.batch(player.sequence.stamp(message))
+		default:
+			select {
+			case <-player.Done:
+				closeConnection(player)
+				return
+			case message = <-player.SendChan:
+				message = player.batch(player.sequence.stamp(message))
+			case message = <-player.PresenceChan:
+			}
+		}
+
+		if !writeMessage(player, message) {
+			disconnect(player, closeWriteFailed)
+			player.Conn.Close()
+			return
+		}
+	}
+}
+
+// writeMessage пишет одно сообщение с дедлайном Options.WriteWait, false - соединение
+// больше не годится для записи
+func writeMessage(player *Player, message []byte) bool {
+	frameType, frame, err := player.encoding.encode(message)
+	if err != nil {
+		log.Println(err)
+		return true
+	}
+
+	if wait := recordTraffic(player, len(frame), false); wait > 0 {
+		time.Sleep(wait)
+	}
+
+	player.Conn.SetWriteDeadline(time.Now().Add(player.server.opts.WriteWait))
+	if err := player.Conn.WriteMessage(frameType, frame); err != nil {
+		log.Println("Ошибка отправки сообщения:", err)
+		return false
+	}
+	return true
+}
+
+func generateConnectedMsg(player *Player) []byte {
+	payload := Payload{
+		Player:      player,
+		Capacity:    player.server.capacity(),
+		ResumeToken: player.resumeToken,
+		LastSeq:     player.sequence.lastSeq(),
+
+		ProtocolVersion: protocolVersion,
+		Locale:          player.locale,
+	}
+	payloadJson, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("ERROR: failed marshal JSON: generateConnectedMsg, error: %v", err)
+	}
+
+	message := WsMessage{
+		Type:    WsMessageTypeConnected,
+		Payload: payloadJson,
+	}
+
+	bytes, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("ERROR: failed marshal JSON: WsMessage: %v, error: %v", message, err)
+	}
+
+	log.Printf("INFO: generated connected msg: %s", bytes)
+	return bytes
+}
+
+func generateLobbyCreatedMsg(lobby *Lobby) []byte {
+	payload := Payload{
+		Lobby: lobby,
+	}
+	payloadJson, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("ERROR: failed marshal JSON: payload: %v, error: %v", payload, err)
+	}
+
+	message := WsMessage{
+		Type:    WsMessageTypeLobbyCreated,
+		Payload: payloadJson,
+	}
+
+	bytes, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("ERROR: failed marshal JSON: WsMessage: %v, error: %v", message, err)
+	}
+	log.Printf("INFO: generated lobby created msg: %s", bytes)
+	return bytes
+}
+
+func generateMsg(msgType WsMessageType, payload Payload) []byte {
+	payloadJson, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("ERROR: failed marshal JSON: payload: %v, error: %v", payload, err)
+	}
+
+	message := WsMessage{
+		Type:    msgType,
+		Payload: payloadJson,
+	}
+
+	bytes, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("ERROR: failed marshal JSON: WsMessage: %v, error: %v", message, err)
+	}
+	log.Printf("INFO: generated %s msg: %s", msgType, bytes)
+	return bytes
+}
+
+func (s *Server) handlePing(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte(`{"error": "Метод не поддерживается"}`))
+		return
+	}
+
+	s.mu.Lock()
+	onlinePlayersCount, lobbiesCount := len(s.Players), len(s.Lobbies)
+	s.mu.Unlock()
+
+	traffic := s.Bandwidth.stats()
+	latency := s.latency()
+
+	response := struct {
+		OnlinePlayersCount int    `json:"onlinePlayersCount"`
+		LobbiesCount       int    `json:"lobbiesCount"`
+		BytesReceived      int64  `json:"bytesReceived"`
+		BytesSent          int64  `json:"bytesSent"`
+		AvgRttMs           int64  `json:"avgRttMs"`
+		MaxRttMs           int64  `json:"maxRttMs"`
+		Status             string `json:"status"`
+	}{
+		OnlinePlayersCount: onlinePlayersCount,
+		LobbiesCount:       lobbiesCount,
+		BytesReceived:      traffic.BytesReceived,
+		BytesSent:          traffic.BytesSent,
+		AvgRttMs:           latency.AvgRttMs,
+		MaxRttMs:           latency.MaxRttMs,
+		Status:             "alive",
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
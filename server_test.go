@@ -0,0 +1,206 @@
+package guesswho
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// testServer - Server за httptest, останавливается в конце теста
+type testServer struct {
+	*Server
+	url string
+}
+
+func newTestServer(t *testing.T, opts Options) *testServer {
+	t.Helper()
+
+	server, err := NewServer(opts)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	httpServer := httptest.NewServer(server)
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			t.Errorf("Shutdown: %v", err)
+		}
+		httpServer.Close()
+	})
+
+	return &testServer{Server: server, url: httpServer.URL}
+}
+
+// testMessage - сообщение сервера в том виде, в каком его видит клиент
+type testMessage struct {
+	Type    WsMessageType   `json:"type"`
+	Code    ErrorCode       `json:"code"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+func (m testMessage) decode(t *testing.T) Payload {
+	t.Helper()
+
+	var payload Payload
+	if err := json.Unmarshal(m.Payload, &payload); err != nil {
+		t.Fatalf("can't decode %s payload: %v", m.Type, err)
+	}
+	return payload
+}
+
+// testClient - WebSocket клиент, читающий сообщения в фоне
+type testClient struct {
+	t        *testing.T
+	conn     *websocket.Conn
+	messages chan testMessage
+
+	id          string
+	resumeToken string
+}
+
+// dial подключается к /ws с query и ждет Connected
+func (s *testServer) dial(t *testing.T, query string) *testClient {
+	t.Helper()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.url, "http")+"/ws?"+query, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	c := &testClient{t: t, conn: conn, messages: make(chan testMessage, 256)}
+	go c.read()
+
+	connected := c.expect(WsMessageTypeConnected).decode(t)
+	c.id = connected.Player.ID
+	c.resumeToken = connected.ResumeToken
+	return c
+}
+
+func (c *testClient) read() {
+	defer close(c.messages)
+
+	for {
+		_, frame, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		// протокол 4 присылает пакеты сообщений JSON массивом
+		var batch []testMessage
+		if json.Unmarshal(frame, &batch) != nil {
+			var msg testMessage
+			if err := json.Unmarshal(frame, &msg); err != nil {
+				continue
+			}
+			batch = []testMessage{msg}
+		}
+		for _, msg := range batch {
+			c.messages <- msg
+		}
+	}
+}
+
+// send отправляет запрос msgType с payload
+func (c *testClient) send(msgType WsMessageType, payload string) {
+	c.t.Helper()
+
+	if payload == "" {
+		payload = "null"
+	}
+	frame := fmt.Sprintf(`{"type":%q,"payload":%s}`, msgType, payload)
+	if err := c.conn.WriteMessage(websocket.TextMessage, []byte(frame)); err != nil {
+		c.t.Fatalf("send %s: %v", msgType, err)
+	}
+}
+
+// expect пропускает сообщения до первого msgType
+func (c *testClient) expect(msgType WsMessageType) testMessage {
+	c.t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg, ok := <-c.messages:
+			if !ok {
+				c.t.Fatalf("connection closed while waiting for %s", msgType)
+			}
+			if msg.Type == msgType {
+				return msg
+			}
+		case <-timeout:
+			c.t.Fatalf("timed out waiting for %s", msgType)
+		}
+	}
+}
+
+// expectError ждет Error и проверяет его код
+func (c *testClient) expectError(code ErrorCode) {
+	c.t.Helper()
+
+	if msg := c.expect(WsMessageTypeError); msg.Code != code {
+		c.t.Fatalf("got error %s, want %s", msg.Code, code)
+	}
+}
+
+// expectNone проверяет, что за wait не пришло ни одного msgType
+func (c *testClient) expectNone(msgType WsMessageType, wait time.Duration) {
+	c.t.Helper()
+
+	timeout := time.After(wait)
+	for {
+		select {
+		case msg, ok := <-c.messages:
+			if !ok {
+				return
+			}
+			if msg.Type == msgType {
+				c.t.Fatalf("got unexpected %s: %s", msgType, msg.Payload)
+			}
+		case <-timeout:
+			return
+		}
+	}
+}
+
+// createLobby создает лобби с кодом code и настройками по умолчанию, кроме maxPlayers
+func (c *testClient) createLobby(code string, maxPlayers int) {
+	c.t.Helper()
+
+	c.send(WsMessageTypeCreateLobby, fmt.Sprintf(`{"player":{"nickname":"host"},"lobby":{"id":%q},"settings":{"turnTimerSeconds":60,"gameMode":"Classic","characterPack":"default","maxPlayers":%d}}`, code, maxPlayers))
+	c.expect(WsMessageTypeLobbyCreated)
+}
+
+// joinLobby входит в лобби code, но ответ не ждет
+func (c *testClient) joinLobby(code, nickname string) {
+	c.t.Helper()
+
+	c.send(WsMessageTypeJoinLobby, fmt.Sprintf(`{"player":{"nickname":%q},"lobby":{"id":%q}}`, nickname, code))
+}
+
+// testMessageType достает тип из уже сгенерированного сообщения
+func testMessageType(t *testing.T, msg []byte) WsMessageType {
+	t.Helper()
+
+	var message testMessage
+	if err := json.Unmarshal(msg, &message); err != nil {
+		t.Fatalf("can't decode message %s: %v", msg, err)
+	}
+	return message.Type
+}
@@ -0,0 +1,87 @@
+package guesswho
+
+import (
+	"encoding/json"
+	"log"
+)
+
+type GameMode string
+
+const (
+	GameModeClassic GameMode = "Classic"
+)
+
+type LobbySettings struct {
+	TurnTimerSeconds int      `json:"turnTimerSeconds"` // 0 - без таймера
+	GameMode         GameMode `json:"gameMode"`
+	CharacterPack    string   `json:"characterPack"`
+	MaxPlayers       int      `json:"maxPlayers"`
+
+	SpectatorsDisabled bool `json:"spectatorsDisabled"`
+	AutoStart          bool `json:"autoStart"` // старт с отсчетом, когда лобби заполнилось и все готовы
+}
+
+func defaultLobbySettings() LobbySettings {
+	return LobbySettings{
+		TurnTimerSeconds: 60,
+		GameMode:         GameModeClassic,
+		CharacterPack:    "default",
+		MaxPlayers:       2,
+	}
+}
+
+// validate проверяет настройки для лобби, в котором уже playersCount игроков,
+// maxLobbyPlayers - Options.MaxLobbyPlayers
+func (s LobbySettings) validate(playersCount, maxLobbyPlayers int) error {
+	switch {
+	case s.TurnTimerSeconds < 0 || s.TurnTimerSeconds > 600:
+		return protocolError(ErrorCodeInvalidSettings, "ERROR: turn timer must be between 0 and 600 seconds, got %d", s.TurnTimerSeconds)
+	case s.GameMode != GameModeClassic:
+		return protocolError(ErrorCodeInvalidSettings, "ERROR: unknown game mode %s", s.GameMode)
+	case s.CharacterPack == "":
+		return protocolError(ErrorCodeInvalidSettings, "ERROR: character pack is required")
+	case s.MaxPlayers < 2 || s.MaxPlayers > maxLobbyPlayers:
+		return protocolError(ErrorCodeInvalidSettings, "ERROR: max players must be between 2 and %d, got %d", maxLobbyPlayers, s.MaxPlayers)
+	case s.MaxPlayers < playersCount:
+		return protocolError(ErrorCodeInvalidSettings, "ERROR: lobby already has %d players, can't lower max players to %d", playersCount, s.MaxPlayers)
+	}
+	return nil
+}
+
+func handleUpdateLobbySettings(player *Player, payloadJson json.RawMessage) {
+	var request UpdateLobbySettingsRequest
+	if !decodeRequest(player, payloadJson, &request) {
+		return
+	}
+
+	player.withLobby(func(lobby *Lobby) {
+		if !player.IsHost {
+			player.sendError(ErrorCodeNotHost, "ERROR: only the host can change lobby settings")
+			return
+		}
+
+		if lobby.InGame {
+			player.sendError(ErrorCodeGameInProgress, "ERROR: can't change settings while game is in progress")
+			return
+		}
+		if err := request.Settings.validate(len(lobby.Players), player.server.opts.MaxLobbyPlayers); err != nil {
+			player.sendErr(err)
+			return
+		}
+		lobby.Settings = *request.Settings
+
+		if request.Settings.SpectatorsDisabled {
+			lobby.removeSpectators()
+		}
+
+		lobby.maybeAutoStart()
+
+		log.Printf("INFO: lobby %s settings updated: %+v", lobby.ID, *request.Settings)
+		lobby.logEvent(LobbyEventSettingsChanged, player, "")
+
+		lobby.broadcast(generateMsg(WsMessageTypeLobbySettingsUpdated, Payload{Lobby: lobby, Settings: request.Settings}))
+
+		// maxPlayers мог вырасти
+		lobby.promoteQueued()
+	})
+}
@@ -0,0 +1,39 @@
+package guesswho
+
+import (
+	"context"
+	"log"
+)
+
+// Остановка сервера: Shutdown отменяет контекст сервера. На него подписан
+// каждый игрок (см. newPlayer), поэтому каждое соединение получает disconnect
+// с ServerShutdown ровно один раз, а фоновые горутины сервера выходят. Новые
+// соединения после этого получают 503, а Shutdown ждет, пока игроки получат
+// close frame, но не дольше ctx. HTTP сервер, в который встроен Server,
+// останавливает вызывающий - после Shutdown, иначе SSE потоки не дадут ему
+// завершиться
+
+// Shutdown отключает всех игроков и ждет закрытия их соединений, ошибка -
+// ctx истек раньше. Повторные вызовы только ждут
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.stop()
+
+	closed := make(chan struct{})
+	go func() {
+		s.connections.Wait()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+		log.Println("INFO: all connections closed")
+		return nil
+	case <-ctx.Done():
+		log.Println("WARNING: shutdown timeout expired, some connections are still open")
+		return ctx.Err()
+	}
+}
+
+func (s *Server) shuttingDown() bool {
+	return s.ctx.Err() != nil
+}
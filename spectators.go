@@ -0,0 +1,97 @@
+package guesswho
+
+import (
+	"encoding/json"
+	"log"
+)
+
+type SpectatorsInfo struct {
+	Count int       `json:"count"`
+	List  []*Player `json:"list"`
+}
+
+// joinSpectator сажает игрока в лобби зрителем, вызывается только из хаба
+func (l *Lobby) joinSpectator(player *Player) error {
+	if l.IsLocked {
+		return protocolError(ErrorCodeLobbyLocked, "ERROR: lobby with id %s is locked", l.ID)
+	}
+	if _, banned := l.banned[player.ID]; banned {
+		return protocolError(ErrorCodeBanned, "ERROR: player is banned from lobby with id %s", l.ID)
+	}
+	if l.Settings.SpectatorsDisabled {
+		return protocolError(ErrorCodeSpectatorsDisabled, "ERROR: spectators are disabled in lobby with id %s", l.ID)
+	}
+	if !player.claimLobby(l) {
+		return protocolError(ErrorCodeAlreadyInLobby, "ERROR: player is already in a lobby")
+	}
+
+	player.IsHost = false
+	player.IsReady = false
+	player.IsSpectator = true
+	l.Spectators = append(l.Spectators, player)
+	l.touch()
+
+	return nil
+}
+
+func handleJoinAsSpectator(player *Player, payloadJson json.RawMessage) {
+	var request JoinAsSpectatorRequest
+	if !decodeRequest(player, payloadJson, &request) {
+		return
+	}
+
+	if player.lobby() != nil {
+		player.sendError(ErrorCodeAlreadyInLobby, "ERROR: player is already in a lobby")
+		return
+	}
+
+	if err := player.applyProfile(request.Player); err != nil {
+		player.sendErr(err)
+		return
+	}
+
+	err := player.server.withLobby(request.Lobby.ID, func(lobby *Lobby) error {
+		if err := lobby.joinSpectator(player); err != nil {
+			return err
+		}
+
+		log.Printf("INFO: player %s joined lobby %s as spectator", player.ID, lobby.ID)
+		lobby.logEvent(LobbyEventSpectatorJoined, player, "")
+
+		player.send(lobby.snapshot(WsMessageTypeSpectatorJoined, player))
+		lobby.broadcastSpectators()
+		return nil
+	})
+	if err != nil {
+		player.sendErr(err)
+	}
+}
+
+// broadcastSpectators рассылает лобби текущий список и число зрителей
+func (l *Lobby) broadcastSpectators() {
+	spectators := &SpectatorsInfo{
+		Count: len(l.Spectators),
+		List:  append([]*Player{}, l.Spectators...),
+	}
+
+	l.broadcast(generateMsg(WsMessageTypeSpectatorsChanged, Payload{Lobby: l, Spectators: spectators}))
+}
+
+// removeSpectators выгоняет всех зрителей, когда хост их отключил
+func (l *Lobby) removeSpectators() {
+	spectators := append([]*Player(nil), l.Spectators...)
+
+	if len(spectators) == 0 {
+		return
+	}
+
+	msg := generateMsg(WsMessageTypeKickedFromLobby, Payload{Lobby: &Lobby{ID: l.ID}})
+	for _, spectator := range spectators {
+		if l.removePlayer(spectator) {
+			spectator.IsSpectator = false
+			spectator.send(msg)
+		}
+	}
+
+	l.broadcastSpectators()
+}
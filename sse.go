@@ -0,0 +1,292 @@
+package guesswho
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Запасной транспорт для клиентов за прокси, которые не пропускают WebSocket:
+// сообщения сервера идут потоком server-sent events (GET /sse), сообщения
+// клиента - отдельными POST /sse/{stream}. Первое событие потока, stream,
+// содержит id для POST, дальше идут обычные сообщения протокола в data.
+// Сообщения клиента проходят тот же receive, что и кадры WebSocket
+
+// Transport - то, через что writer и qualityReporter пишут игроку,
+// *websocket.Conn реализует его как есть
+type Transport interface {
+	WriteMessage(messageType int, data []byte) error
+	WriteControl(messageType int, data []byte, deadline time.Time) error
+	SetReadDeadline(t time.Time) error
+	SetWriteDeadline(t time.Time) error
+	Close() error
+}
+
+// сколько POST сообщений ждут обработки, лишние получают 429
+const sseInboxSize = 16
+
+var errStreamClosed = errors.New("sse: stream closed")
+
+type sseStream struct {
+	id     string
+	w      http.ResponseWriter
+	rc     *http.ResponseController
+	inbox  chan []byte
+	onPong func(appData string)
+
+	mu     sync.Mutex // писать в поток может writer и qualityReporter
+	closed chan struct{}
+}
+
+// открытые SSE потоки по id, чтобы POST нашел своего игрока
+type sseRegistry struct {
+	byID map[string]*sseStream
+	mu   sync.Mutex
+}
+
+// write пишет кусок потока и сразу отправляет его клиенту. После Close GET
+// обработчик уже мог вернуться, поэтому в закрытый поток не пишем
+func (s *sseStream) write(chunk string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-s.closed:
+		return errStreamClosed
+	default:
+	}
+
+	if _, err := io.WriteString(s.w, chunk); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+func (s *sseStream) event(name string, data []byte) error {
+	if name == "" {
+		return s.write(fmt.Sprintf("data: %s\n\n", data))
+	}
+	return s.write(fmt.Sprintf("event: %s\ndata: %s\n\n", name, data))
+}
+
+// WriteMessage отправляет сообщение протокола, кадры здесь всегда JSON
+func (s *sseStream) WriteMessage(_ int, data []byte) error {
+	return s.event("", data)
+}
+
+// WriteControl переводит управляющие кадры WebSocket в термины SSE: ping -
+// комментарий, который держит прокси открытым (pong у SSE нет, поэтому успешная
+// запись сама считается pong'ом), close - событие close с кодом и причиной,
+// после которого поток завершается
+func (s *sseStream) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	if err := s.SetWriteDeadline(deadline); err != nil {
+		return err
+	}
+
+	switch messageType {
+	case websocket.PingMessage:
+		err := s.write(": ping\n\n")
+		if err == nil && s.onPong != nil {
+			s.onPong(string(data))
+		}
+		return err
+	case websocket.CloseMessage:
+		reason := struct {
+			Code int    `json:"code"`
+			Text string `json:"reason"`
+		}{}
+		if len(data) >= 2 {
+			reason.Code = int(binary.BigEndian.Uint16(data))
+			reason.Text = string(data[2:])
+		}
+		closeJson, _ := json.Marshal(reason)
+		err := s.event("close", closeJson)
+		s.Close()
+		return err
+	}
+	return nil
+}
+
+// SetReadDeadline ничего не делает: клиент SSE может долго ничего не
+// присылать, живость потока проверяют записи пингов
+func (s *sseStream) SetReadDeadline(time.Time) error {
+	return nil
+}
+
+func (s *sseStream) SetWriteDeadline(t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-s.closed:
+		return errStreamClosed
+	default:
+	}
+	return s.rc.SetWriteDeadline(t)
+}
+
+// Close завершает поток, GET обработчик вернется и закроет ответ
+func (s *sseStream) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-s.closed:
+	default:
+		close(s.closed)
+	}
+	return nil
+}
+
+// checkSSEOrigin пропускает к SSE только Origin, которые CheckOrigin пустил бы
+// к WebSocket, и открывает CORS именно для них. false - 403 уже отправлен
+func (s *Server) checkSSEOrigin(w http.ResponseWriter, r *http.Request) bool {
+	if !s.upgrader.CheckOrigin(r) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"error": "Источник запроса не разрешен"}`))
+		return false
+	}
+
+	if origin := r.Header.Get("Origin"); origin != "" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Add("Vary", "Origin")
+	}
+	return true
+}
+
+func (s *Server) handleSSE(w http.ResponseWriter, r *http.Request) {
+	if !s.checkSSEOrigin(w, r) {
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.connections.Add(1)
+	defer s.connections.Done()
+
+	if s.shuttingDown() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	stream := &sseStream{
+		id:     newResumeToken(),
+		w:      w,
+		rc:     http.NewResponseController(w),
+		inbox:  make(chan []byte, sseInboxSize),
+		closed: make(chan struct{}),
+	}
+
+	streamJson, _ := json.Marshal(struct {
+		ID string `json:"id"`
+	}{stream.id})
+	if err := stream.event("stream", streamJson); err != nil {
+		log.Printf("ERROR: can't start server-sent events stream, error: %v", err)
+		return
+	}
+
+	player, setupErrs := s.newPlayer(r.Context(), stream, r.URL.Query())
+	if player.encoding != EncodingJSON {
+		player.encoding = EncodingJSON
+		setupErrs = append(setupErrs, protocolError(ErrorCodeInvalidRequest, "ERROR: server-sent events transport supports only json encoding"))
+	}
+	stream.onPong = player.heartbeat.onPong
+
+	s.sseStreams.mu.Lock()
+	s.sseStreams.byID[stream.id] = stream
+	s.sseStreams.mu.Unlock()
+
+	defer func() {
+		s.sseStreams.mu.Lock()
+		delete(s.sseStreams.byID, stream.id)
+		s.sseStreams.mu.Unlock()
+	}()
+
+	log.Printf("INFO: player %s connected over server-sent events", player.ID)
+
+	player.connect(r.URL.Query(), setupErrs)
+
+	reason := closeByClient
+loop:
+	for {
+		select {
+		case message := <-stream.inbox:
+			if closing, stop := player.receive(websocket.TextMessage, message); stop {
+				reason = closing
+				break loop
+			}
+		case <-stream.closed:
+			break loop
+		case <-player.Done:
+			break loop
+		}
+	}
+
+	disconnect(player, reason)
+	<-player.writerDone
+}
+
+// handleSSEMessage принимает одно сообщение клиента для потока {stream}
+func (s *Server) handleSSEMessage(w http.ResponseWriter, r *http.Request) {
+	if !s.checkSSEOrigin(w, r) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Access-Control-Allow-Methods", "POST")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte(`{"error": "Метод не поддерживается"}`))
+		return
+	}
+
+	s.sseStreams.mu.Lock()
+	stream, exists := s.sseStreams.byID[r.PathValue("stream")]
+	s.sseStreams.mu.Unlock()
+
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "Поток не найден"}`))
+		return
+	}
+
+	message, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.opts.MaxMessageSize))
+	if err != nil {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		w.Write([]byte(`{"error": "Сообщение слишком большое"}`))
+		return
+	}
+
+	select {
+	case stream.inbox <- message:
+		w.WriteHeader(http.StatusAccepted)
+	case <-stream.closed:
+		w.WriteHeader(http.StatusGone)
+		w.Write([]byte(`{"error": "Поток закрыт"}`))
+	default:
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error": "Слишком много сообщений"}`))
+	}
+}
@@ -0,0 +1,54 @@
+package guesswho
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// SSE пускает только Origin, которые CheckOrigin пустил бы к WebSocket
+func TestSSECheckOrigin(t *testing.T) {
+	opts := DefaultOptions()
+	opts.CheckOrigin = func(r *http.Request) bool {
+		return r.Header.Get("Origin") == "https://good.example"
+	}
+	server := newTestServer(t, opts)
+
+	for _, tc := range []struct {
+		name   string
+		method string
+		path   string
+		origin string
+		status int
+	}{
+		{"stream from other origin", http.MethodGet, "/sse", "https://evil.example", http.StatusForbidden},
+		{"message from other origin", http.MethodPost, "/sse/nope", "https://evil.example", http.StatusForbidden},
+		{"preflight from other origin", http.MethodOptions, "/sse/nope", "https://evil.example", http.StatusForbidden},
+		{"stream", http.MethodGet, "/sse", "https://good.example", http.StatusOK},
+		{"message", http.MethodPost, "/sse/nope", "https://good.example", http.StatusNotFound},
+	} {
+		req, err := http.NewRequest(tc.method, server.url+tc.path, strings.NewReader("{}"))
+		if err != nil {
+			t.Fatalf("NewRequest: %v", err)
+		}
+		req.Header.Set("Origin", tc.origin)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != tc.status {
+			t.Errorf("%s: got status %d, want %d", tc.name, resp.StatusCode, tc.status)
+		}
+
+		wantOrigin := ""
+		if tc.status != http.StatusForbidden {
+			wantOrigin = tc.origin
+		}
+		if origin := resp.Header.Get("Access-Control-Allow-Origin"); origin != wantOrigin {
+			t.Errorf("%s: got Access-Control-Allow-Origin %q, want %q", tc.name, origin, wantOrigin)
+		}
+	}
+}
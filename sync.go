@@ -0,0 +1,91 @@
+package guesswho
+
+import (
+	"encoding/json"
+)
+
+// SyncState - все, что сервер знает о состоянии игрока, ответ на RequestSync.
+// Клиент заменяет им свое состояние целиком после переподключения или пропуска сообщений
+type SyncState struct {
+	Player        *Player      `json:"player"`
+	Lobby         *Lobby       `json:"lobby,omitempty"` // с игроками, зрителями, настройками и inGame
+	QueuedLobbyID string       `json:"queuedLobbyId,omitempty"`
+	QueuePosition int          `json:"queuePosition,omitempty"`
+	Invitations   []Invitation `json:"invitations,omitempty"` // ожидающие ответа, входящие и исходящие
+	Capacity      *Capacity    `json:"capacity,omitempty"`
+}
+
+// queuePosition ищет лобби, в очереди которого стоит игрок
+func (s *Server) queuePosition(player *Player) (string, int) {
+	s.mu.Lock()
+	lobbies := make([]*Lobby, 0, len(s.Lobbies))
+	for _, lobby := range s.Lobbies {
+		lobbies = append(lobbies, lobby)
+	}
+	s.mu.Unlock()
+
+	for _, lobby := range lobbies {
+		position := 0
+		lobby.do(func() {
+			for i, queued := range lobby.queue {
+				if queued == player {
+					position = i + 1
+					return
+				}
+			}
+		})
+		if position > 0 {
+			return lobby.ID, position
+		}
+	}
+	return "", 0
+}
+
+func (s *Server) pendingInvitations(player *Player) []Invitation {
+	s.invitations.mu.Lock()
+	defer s.invitations.mu.Unlock()
+
+	var pending []Invitation
+	for _, invitation := range s.invitations.byID {
+		if invitation.from == player || invitation.to == player {
+			pending = append(pending, *invitation)
+		}
+	}
+	return pending
+}
+
+func handleRequestSync(player *Player, _ json.RawMessage) {
+	sendSyncState(player)
+}
+
+// sendSyncState отправляет игроку его полное состояние
+func sendSyncState(player *Player) {
+	s := player.server
+	// вне хаба флаги игрока читать нельзя, их может менять хаб лобби, куда его
+	// как раз сажает matchmaker
+	state := &SyncState{
+		Player:      player.profile(),
+		Invitations: s.pendingInvitations(player),
+		Capacity:    s.capacity(),
+	}
+	state.QueuedLobbyID, state.QueuePosition = s.queuePosition(player)
+
+	// лобби сериализуем в его хабе, как в snapshot; пока событие ждало
+	// очереди, игрок мог из лобби выйти
+	if lobby := player.lobby(); lobby != nil {
+		sent := false
+		lobby.do(func() {
+			if player.lobby() == lobby {
+				state.Player = player
+				state.Lobby = lobby
+				player.send(generateMsg(WsMessageTypeSyncState, Payload{Sync: state}))
+				sent = true
+			}
+		})
+		if sent {
+			return
+		}
+	}
+
+	player.send(generateMsg(WsMessageTypeSyncState, Payload{Sync: state}))
+}
@@ -0,0 +1,73 @@
+package guesswho
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Синхронизация часов: клиент шлет TimeSync со своим временем отправки, сервер
+// отвечает TimeSync с временем получения и отправки по своим часам. По четырем
+// отметкам клиент считает смещение часов, как в NTP:
+//
+//	offset = ((serverReceivedAt - clientTime) + (serverSentAt - время получения ответа)) / 2
+//
+// и показывает таймер хода по серверному времени. Все отметки - миллисекунды Unix
+
+type TimeSync struct {
+	ClientTime       int64 `json:"clientTime"`
+	ServerReceivedAt int64 `json:"serverReceivedAt"`
+	ServerSentAt     int64 `json:"serverSentAt"`
+	RttMs            int64 `json:"rttMs"` // сглаженный RTT соединения по пингам сервера
+}
+
+func handleTimeSync(player *Player, payloadJson json.RawMessage) {
+	receivedAt := time.Now()
+
+	var request TimeSyncRequest
+	if !decodeRequest(player, payloadJson, &request) {
+		return
+	}
+
+	sync := &TimeSync{
+		ClientTime:       request.ClientTime,
+		ServerReceivedAt: receivedAt.UnixMilli(),
+		RttMs:            player.heartbeat.smoothedRTT().Milliseconds(),
+	}
+	sync.ServerSentAt = time.Now().UnixMilli()
+
+	player.send(generateMsg(WsMessageTypeTimeSync, Payload{TimeSync: sync}))
+}
+
+// LatencyStats - RTT подключенных игроков для /ping
+type LatencyStats struct {
+	AvgRttMs int64 `json:"avgRttMs"`
+	MaxRttMs int64 `json:"maxRttMs"`
+}
+
+// latency собирает RTT игроков, которые уже ответили хотя бы на один пинг
+func (s *Server) latency() LatencyStats {
+	s.mu.Lock()
+	players := make([]*Player, 0, len(s.Players))
+	for _, player := range s.Players {
+		players = append(players, player)
+	}
+	s.mu.Unlock()
+
+	var stats LatencyStats
+	var total time.Duration
+	var measured int
+	for _, player := range players {
+		rtt := player.heartbeat.smoothedRTT()
+		if rtt == 0 {
+			continue
+		}
+		total += rtt
+		measured++
+		stats.MaxRttMs = max(stats.MaxRttMs, rtt.Milliseconds())
+	}
+
+	if measured > 0 {
+		stats.AvgRttMs = (total / time.Duration(measured)).Milliseconds()
+	}
+	return stats
+}
@@ -0,0 +1,379 @@
+// tsgen генерирует TypeScript типы протокола из структур сервера, запускается
+// через go generate (см. schema.go). Читает исходники пакета как AST: типы
+// сообщений берет из реестров clientMessages, serverMessages и serverEnvelopes,
+// поля - из json тегов, строковые перечисления - из типизированных констант.
+// С -client из тех же реестров пишет константы типов сообщений и кодов ошибок
+// для Go клиента
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type message struct {
+	Const   string // имя константы WsMessageType
+	Type    string // значение WsMessageType
+	Payload string // имя Go типа payload, пусто - без payload
+}
+
+type generator struct {
+	structs   map[string]*ast.StructType
+	aliases   map[string]ast.Expr // именованные не-структуры, например GameMode string
+	constants map[string][]string // тип -> значения его констант
+	names     map[string][]string // тип -> имена его констант
+	values    map[string]string   // имя константы -> значение
+	emitted   map[string]bool
+	order     []string
+
+	idempotent map[string]bool // типы сообщений, принимающие idempotencyKey
+	out        strings.Builder
+}
+
+func main() {
+	dir := flag.String("dir", ".", "directory with the server package")
+	out := flag.String("out", "web/protocol.ts", "TypeScript file to write")
+	clientOut := flag.String("client", "", "Go file with message type and error code constants for the client package, empty - don't write")
+	flag.Parse()
+
+	g := &generator{
+		structs:   make(map[string]*ast.StructType),
+		aliases:   make(map[string]ast.Expr),
+		constants: make(map[string][]string),
+		names:     make(map[string][]string),
+		values:    make(map[string]string),
+		emitted:   make(map[string]bool),
+	}
+
+	files, err := g.parse(*dir)
+	if err != nil {
+		log.Fatalf("ERROR: can't parse %s, error: %v", *dir, err)
+	}
+
+	client := g.registry(files, "clientMessages")
+	server := g.registry(files, "serverMessages")
+	envelopes := g.registry(files, "serverEnvelopes")
+	if len(client) == 0 || len(server) == 0 {
+		log.Fatalf("ERROR: message registries not found in %s", *dir)
+	}
+
+	g.idempotent = make(map[string]bool)
+	for _, msg := range g.registry(files, "idempotentMessages") {
+		g.idempotent[msg.Type] = true
+	}
+
+	g.render(client, server, envelopes)
+
+	if err := os.MkdirAll(filepath.Dir(*out), 0o755); err != nil {
+		log.Fatalf("ERROR: can't create directory for %s, error: %v", *out, err)
+	}
+	if err := os.WriteFile(*out, []byte(g.out.String()), 0o644); err != nil {
+		log.Fatalf("ERROR: can't write %s, error: %v", *out, err)
+	}
+	log.Printf("INFO: wrote %d types and %d messages to %s", len(g.order), len(client)+len(server)+len(envelopes), *out)
+
+	if *clientOut == "" {
+		return
+	}
+	source, err := g.clientConstants(client, server, envelopes)
+	if err != nil {
+		log.Fatalf("ERROR: can't format constants for %s, error: %v", *clientOut, err)
+	}
+	if err := os.WriteFile(*clientOut, source, 0o644); err != nil {
+		log.Fatalf("ERROR: can't write %s, error: %v", *clientOut, err)
+	}
+	log.Printf("INFO: wrote client constants to %s", *clientOut)
+}
+
+func (g *generator) parse(dir string) ([]*ast.File, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+			switch gen.Tok {
+			case token.TYPE:
+				for _, spec := range gen.Specs {
+					typeSpec := spec.(*ast.TypeSpec)
+					if structType, ok := typeSpec.Type.(*ast.StructType); ok {
+						g.structs[typeSpec.Name.Name] = structType
+					} else {
+						g.aliases[typeSpec.Name.Name] = typeSpec.Type
+					}
+				}
+			case token.CONST:
+				g.collectConstants(gen)
+			}
+		}
+	}
+	return files, nil
+}
+
+// collectConstants запоминает строковые константы с явным типом
+func (g *generator) collectConstants(gen *ast.GenDecl) {
+	for _, spec := range gen.Specs {
+		valueSpec := spec.(*ast.ValueSpec)
+		typeName, ok := valueSpec.Type.(*ast.Ident)
+		if !ok || len(valueSpec.Values) != len(valueSpec.Names) {
+			continue
+		}
+		for i, name := range valueSpec.Names {
+			literal, ok := valueSpec.Values[i].(*ast.BasicLit)
+			if !ok || literal.Kind != token.STRING {
+				continue
+			}
+			value, _ := strconv.Unquote(literal.Value)
+			g.values[name.Name] = value
+			g.constants[typeName.Name] = append(g.constants[typeName.Name], value)
+			g.names[typeName.Name] = append(g.names[typeName.Name], name.Name)
+		}
+	}
+}
+
+// registry читает реестр сообщений вида map[WsMessageType]any{Const: Type{}, ...}
+func (g *generator) registry(files []*ast.File, name string) []message {
+	var messages []message
+	for _, file := range files {
+		ast.Inspect(file, func(node ast.Node) bool {
+			valueSpec, ok := node.(*ast.ValueSpec)
+			if !ok || len(valueSpec.Names) != 1 || valueSpec.Names[0].Name != name || len(valueSpec.Values) != 1 {
+				return true
+			}
+			literal, ok := valueSpec.Values[0].(*ast.CompositeLit)
+			if !ok {
+				return false
+			}
+			for _, element := range literal.Elts {
+				pair := element.(*ast.KeyValueExpr)
+				key := pair.Key.(*ast.Ident).Name
+				msg := message{Const: key, Type: g.values[key]}
+				if payload, ok := pair.Value.(*ast.CompositeLit); ok && payload.Type != nil {
+					msg.Payload = payload.Type.(*ast.Ident).Name
+				}
+				messages = append(messages, msg)
+			}
+			return false
+		})
+	}
+
+	sort.Slice(messages, func(i, j int) bool { return messages[i].Type < messages[j].Type })
+	return messages
+}
+
+// tsType переводит Go тип поля в TypeScript, попутно ставя в очередь именованные типы
+func (g *generator) tsType(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return g.tsType(t.X)
+	case *ast.ArrayType:
+		return g.tsType(t.Elt) + "[]"
+	case *ast.MapType:
+		return "Record<" + g.tsType(t.Key) + ", " + g.tsType(t.Value) + ">"
+	case *ast.SelectorExpr:
+		switch t.Sel.Name {
+		case "Time":
+			return "string"
+		case "RawMessage":
+			return "unknown"
+		}
+	case *ast.Ident:
+		switch t.Name {
+		case "string":
+			return "string"
+		case "bool":
+			return "boolean"
+		case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "float32", "float64":
+			return "number"
+		case "any":
+			return "unknown"
+		}
+		if _, ok := g.structs[t.Name]; ok {
+			g.need(t.Name)
+			return t.Name
+		}
+		if _, ok := g.constants[t.Name]; ok {
+			g.need(t.Name)
+			return t.Name
+		}
+		if underlying, ok := g.aliases[t.Name]; ok {
+			return g.tsType(underlying)
+		}
+	}
+	return "unknown"
+}
+
+func (g *generator) need(name string) {
+	if !g.emitted[name] {
+		g.emitted[name] = true
+		g.order = append(g.order, name)
+	}
+}
+
+// hasRequired - в структуре есть поля без omitempty
+func (g *generator) hasRequired(name string) bool {
+	for _, field := range g.fields(name) {
+		if !field.optional {
+			return true
+		}
+	}
+	return false
+}
+
+type field struct {
+	name     string
+	expr     ast.Expr
+	optional bool
+}
+
+func (g *generator) fields(name string) []field {
+	var fields []field
+	for _, f := range g.structs[name].Fields.List {
+		if len(f.Names) == 0 || f.Tag == nil {
+			continue
+		}
+		tag, _ := strconv.Unquote(f.Tag.Value)
+		jsonName, options, _ := strings.Cut(reflect.StructTag(tag).Get("json"), ",")
+		if jsonName == "-" || jsonName == "" || !ast.IsExported(f.Names[0].Name) {
+			continue
+		}
+		fields = append(fields, field{
+			name:     jsonName,
+			expr:     f.Type,
+			optional: strings.Contains(options, "omitempty"),
+		})
+	}
+	return fields
+}
+
+func (g *generator) messageUnion(name string, messages []message, fromClient bool) {
+	fmt.Fprintf(&g.out, "export type %s =\n", name)
+	for i, msg := range messages {
+		fmt.Fprintf(&g.out, "  | { type: %q", msg.Type)
+		if fromClient {
+			g.out.WriteString("; requestId?: string")
+			if g.idempotent[msg.Type] {
+				g.out.WriteString("; idempotencyKey?: string")
+			}
+		} else {
+			g.out.WriteString("; seq?: number") // у фоновых сообщений номера нет
+		}
+		if msg.Payload != "" {
+			optional := ""
+			if fromClient && !g.hasRequired(msg.Payload) {
+				optional = "?"
+			}
+			fmt.Fprintf(&g.out, "; payload%s: %s", optional, g.tsType(&ast.Ident{Name: msg.Payload}))
+		}
+		g.out.WriteString(" }")
+		if i == len(messages)-1 {
+			g.out.WriteString(";")
+		}
+		g.out.WriteString("\n")
+	}
+	g.out.WriteString("\n")
+}
+
+func (g *generator) render(client, server, envelopes []message) {
+	g.out.WriteString("// Code generated by tools/tsgen from the server message structs. DO NOT EDIT.\n\n")
+
+	g.messageUnion("ClientMessage", client, true)
+	g.messageUnion("ServerPayloadMessage", server, false)
+
+	g.out.WriteString("export type ServerMessage =\n  | ServerPayloadMessage")
+	for _, envelope := range envelopes {
+		fmt.Fprintf(&g.out, "\n  | (%s & { type: %q })", g.tsType(&ast.Ident{Name: envelope.Payload}), envelope.Type)
+	}
+	g.out.WriteString(";\n\n")
+
+	// с протокола 4 накопившиеся сообщения приходят одним кадром-массивом
+	g.out.WriteString("export type ServerFrame = ServerMessage | ServerMessage[];\n")
+
+	// типы добавляются в очередь по мере обхода полей
+	for i := 0; i < len(g.order); i++ {
+		name := g.order[i]
+		g.out.WriteString("\n")
+
+		if values, ok := g.constants[name]; ok {
+			quoted := make([]string, len(values))
+			for j, value := range values {
+				quoted[j] = strconv.Quote(value)
+			}
+			fmt.Fprintf(&g.out, "export type %s = %s;\n", name, strings.Join(quoted, " | "))
+			continue
+		}
+
+		fmt.Fprintf(&g.out, "export interface %s {\n", name)
+		for _, f := range g.fields(name) {
+			optional := ""
+			if f.optional {
+				optional = "?"
+			}
+			fmt.Fprintf(&g.out, "  %s%s: %s;\n", f.name, optional, g.tsType(f.expr))
+		}
+		g.out.WriteString("}\n")
+	}
+}
+
+// clientConstants собирает исходник пакета client с константами всех типов
+// сообщений из реестров и всех кодов ошибок
+func (g *generator) clientConstants(messageLists ...[]message) ([]byte, error) {
+	var out strings.Builder
+	out.WriteString("// Code generated by tools/tsgen from the server message registries. DO NOT EDIT.\n\n")
+	out.WriteString("package client\n\n")
+
+	seen := make(map[string]bool)
+	var messages []message
+	for _, list := range messageLists {
+		for _, msg := range list {
+			if !seen[msg.Const] {
+				seen[msg.Const] = true
+				messages = append(messages, msg)
+			}
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].Type < messages[j].Type })
+
+	out.WriteString("// типы сообщений протокола, см. WsMessageType сервера\nconst (\n")
+	for _, msg := range messages {
+		fmt.Fprintf(&out, "%s = %q\n", "MessageType"+strings.TrimPrefix(msg.Const, "WsMessageType"), msg.Type)
+	}
+	out.WriteString(")\n\n")
+
+	out.WriteString("// коды ошибок сервера, см. Error.Code\nconst (\n")
+	for _, name := range g.names["ErrorCode"] {
+		fmt.Fprintf(&out, "%s = %q\n", name, g.values[name])
+	}
+	out.WriteString(")\n")
+
+	return format.Source([]byte(out.String()))
+}
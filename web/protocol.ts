@@ -0,0 +1,309 @@
+// Code generated by tools/tsgen from the server message structs. DO NOT EDIT.
+
+export type ClientMessage =
+  | { type: "AcceptInvitation"; requestId?: string; payload: RespondToInvitationRequest }
+  | { type: "CancelFindMatch"; requestId?: string }
+  | { type: "CreateLobby"; requestId?: string; idempotencyKey?: string; payload: CreateLobbyRequest }
+  | { type: "DeclineInvitation"; requestId?: string; payload: RespondToInvitationRequest }
+  | { type: "FindMatch"; requestId?: string; payload?: FindMatchRequest }
+  | { type: "GetLobbyEvents"; requestId?: string }
+  | { type: "Hello"; requestId?: string; payload: HelloRequest }
+  | { type: "InvitePlayer"; requestId?: string; payload: InvitePlayerRequest }
+  | { type: "JoinAsSpectator"; requestId?: string; payload: JoinAsSpectatorRequest }
+  | { type: "JoinLobby"; requestId?: string; idempotencyKey?: string; payload: JoinLobbyRequest }
+  | { type: "KickPlayer"; requestId?: string; payload: KickPlayerRequest }
+  | { type: "LeaveLobbyQueue"; requestId?: string; payload: LeaveLobbyQueueRequest }
+  | { type: "LockLobby"; requestId?: string }
+  | { type: "PlayerQuit"; requestId?: string }
+  | { type: "PlayerReady"; requestId?: string }
+  | { type: "PlayerUnready"; requestId?: string }
+  | { type: "QueueForLobby"; requestId?: string; payload: QueueForLobbyRequest }
+  | { type: "RejoinLobby"; requestId?: string; payload: RejoinLobbyRequest }
+  | { type: "RequestSync"; requestId?: string }
+  | { type: "StartGame"; requestId?: string }
+  | { type: "TimeSync"; requestId?: string; payload: TimeSyncRequest }
+  | { type: "TransferHost"; requestId?: string; payload: TransferHostRequest }
+  | { type: "UnlockLobby"; requestId?: string }
+  | { type: "UpdateLobbySettings"; requestId?: string; payload: UpdateLobbySettingsRequest };
+
+export type ServerPayloadMessage =
+  | { type: "AutoStartCancelled"; seq?: number; payload: Payload }
+  | { type: "AutoStartCountdown"; seq?: number; payload: Payload }
+  | { type: "CapacityUpdated"; seq?: number; payload: Payload }
+  | { type: "Connected"; seq?: number; payload: Payload }
+  | { type: "ConnectionQuality"; seq?: number; payload: Payload }
+  | { type: "GameStarted"; seq?: number; payload: Payload }
+  | { type: "Hello"; seq?: number; payload: Payload }
+  | { type: "HostChanged"; seq?: number; payload: Payload }
+  | { type: "InvitationReceived"; seq?: number; payload: Payload }
+  | { type: "InvitationUpdated"; seq?: number; payload: Payload }
+  | { type: "KickedFromLobby"; seq?: number; payload: Payload }
+  | { type: "LobbyClosed"; seq?: number; payload: Payload }
+  | { type: "LobbyCreated"; seq?: number; payload: Payload }
+  | { type: "LobbyEvents"; seq?: number; payload: Payload }
+  | { type: "LobbyJoined"; seq?: number; payload: Payload }
+  | { type: "LobbyLockChanged"; seq?: number; payload: Payload }
+  | { type: "LobbyQueuePosition"; seq?: number; payload: Payload }
+  | { type: "LobbySettingsUpdated"; seq?: number; payload: Payload }
+  | { type: "LobbyStateDelta"; seq?: number; payload: LobbyStateDelta }
+  | { type: "MatchFound"; seq?: number; payload: Payload }
+  | { type: "MatchmakingCancelled"; seq?: number; payload: Payload }
+  | { type: "MatchmakingQueued"; seq?: number; payload: Payload }
+  | { type: "MatchmakingTimedOut"; seq?: number; payload: Payload }
+  | { type: "PlayerDisconnected"; seq?: number; payload: Payload }
+  | { type: "PlayerKicked"; seq?: number; payload: Payload }
+  | { type: "PlayerLeft"; seq?: number; payload: Payload }
+  | { type: "PlayerReadyChanged"; seq?: number; payload: Payload }
+  | { type: "PlayerReconnected"; seq?: number; payload: Payload }
+  | { type: "PlayerTimedOut"; seq?: number; payload: Payload }
+  | { type: "SpectatorJoined"; seq?: number; payload: Payload }
+  | { type: "SpectatorsChanged"; seq?: number; payload: Payload }
+  | { type: "SyncState"; seq?: number; payload: Payload }
+  | { type: "TimeSync"; seq?: number; payload: Payload }
+  | { type: "UpgradeRequired"; seq?: number; payload: Payload };
+
+export type ServerMessage =
+  | ServerPayloadMessage
+  | (AckResponse & { type: "Ack" })
+  | (ErrorResponse & { type: "Error" });
+
+export type ServerFrame = ServerMessage | ServerMessage[];
+
+export interface RespondToInvitationRequest {
+  invitation: InvitationRef;
+}
+
+export interface CreateLobbyRequest {
+  player: PlayerProfile;
+  lobby?: LobbyInfo;
+  settings?: LobbySettings;
+}
+
+export interface FindMatchRequest {
+  player?: PlayerProfile;
+  settings?: LobbySettings;
+  lobby?: LobbyInfo;
+}
+
+export interface HelloRequest {
+  protocolVersion: number;
+}
+
+export interface InvitePlayerRequest {
+  player: PlayerRef;
+}
+
+export interface JoinAsSpectatorRequest {
+  player?: PlayerProfile;
+  lobby: LobbyRef;
+}
+
+export interface JoinLobbyRequest {
+  player: PlayerProfile;
+  lobby: LobbyRef;
+  inviteToken?: string;
+}
+
+export interface KickPlayerRequest {
+  player: PlayerRef;
+  ban?: boolean;
+}
+
+export interface LeaveLobbyQueueRequest {
+  lobby: LobbyRef;
+}
+
+export interface QueueForLobbyRequest {
+  player?: PlayerProfile;
+  lobby: LobbyRef;
+}
+
+export interface RejoinLobbyRequest {
+  resumeToken: string;
+}
+
+export interface TimeSyncRequest {
+  clientTime: number;
+}
+
+export interface TransferHostRequest {
+  player: PlayerRef;
+}
+
+export interface UpdateLobbySettingsRequest {
+  settings: LobbySettings;
+}
+
+export interface Payload {
+  lobby?: Lobby;
+  player?: Player;
+  capacity?: Capacity;
+  quality?: ConnectionQuality;
+  settings?: LobbySettings;
+  queuePosition?: number;
+  resumeToken?: string;
+  lastSeq?: number;
+  spectators?: SpectatorsInfo;
+  closeReason?: LobbyCloseReason;
+  events?: LobbyEvent[];
+  countdownSeconds?: number;
+  invitation?: Invitation;
+  protocolVersion?: number;
+  locale?: string;
+  sync?: SyncState;
+  upgrade?: UpgradeRequired;
+  timeSync?: TimeSync;
+}
+
+export interface LobbyStateDelta {
+  lobbyId: string;
+  baseRevision: number;
+  revision: number;
+  changes: Record<string, unknown>;
+}
+
+export interface AckResponse {
+  seq?: number;
+  type: WsMessageType;
+  requestType: WsMessageType;
+  requestId: string;
+}
+
+export interface ErrorResponse {
+  seq?: number;
+  type: WsMessageType;
+  code: ErrorCode;
+  requestType?: WsMessageType;
+  requestId?: string;
+  message?: string;
+  localizedMessage?: string;
+}
+
+export interface InvitationRef {
+  id: string;
+}
+
+export interface PlayerProfile {
+  nickname: string;
+  avatarIdx?: number;
+}
+
+export interface LobbyInfo {
+  id?: string;
+  name?: string;
+  region?: string;
+  isPublic?: boolean;
+}
+
+export interface LobbySettings {
+  turnTimerSeconds: number;
+  gameMode: GameMode;
+  characterPack: string;
+  maxPlayers: number;
+  spectatorsDisabled: boolean;
+  autoStart: boolean;
+}
+
+export interface PlayerRef {
+  id?: string;
+  nickname?: string;
+}
+
+export interface LobbyRef {
+  id: string;
+}
+
+export interface Lobby {
+  id?: string;
+  name?: string;
+  region?: string;
+  players?: Player[];
+  spectators?: Player[];
+  isPublic: boolean;
+  isLocked: boolean;
+  inGame: boolean;
+  settings: LobbySettings;
+  revision: number;
+}
+
+export interface Player {
+  id?: string;
+  nickname?: string;
+  avatarIdx?: number;
+  isHost?: boolean;
+  isReady: boolean;
+  isSpectator?: boolean;
+}
+
+export interface Capacity {
+  loadTier: LoadTier;
+  onlinePlayersCount: number;
+  lobbiesCount: number;
+  lobbyCreationThrottled: boolean;
+  estimatedWaitSeconds: number;
+}
+
+export interface ConnectionQuality {
+  playerId: string;
+  rttMs: number;
+  missedHeartbeats: number;
+  sendQueueDepth: number;
+}
+
+export interface SpectatorsInfo {
+  count: number;
+  list: Player[];
+}
+
+export type LobbyCloseReason = "Empty" | "Idle" | "Admin";
+
+export interface LobbyEvent {
+  type: LobbyEventType;
+  playerId?: string;
+  nickname?: string;
+  details?: string;
+  at: string;
+}
+
+export interface Invitation {
+  id: string;
+  lobbyId: string;
+  from: Player;
+  to: Player;
+  status: InvitationStatus;
+  expiresAt: string;
+}
+
+export interface SyncState {
+  player: Player;
+  lobby?: Lobby;
+  queuedLobbyId?: string;
+  queuePosition?: number;
+  invitations?: Invitation[];
+  capacity?: Capacity;
+}
+
+export interface UpgradeRequired {
+  appVersion?: string;
+  minVersion: string;
+  downloadUrl?: string;
+}
+
+export interface TimeSync {
+  clientTime: number;
+  serverReceivedAt: number;
+  serverSentAt: number;
+  rttMs: number;
+}
+
+export type WsMessageType = "Unknown" | "Error" | "Ack" | "Hello" | "TimeSync" | "CreateLobby" | "JoinLobby" | "PlayerQuit" | "PlayerReady" | "PlayerUnready" | "StartGame" | "KickPlayer" | "UpdateLobbySettings" | "FindMatch" | "CancelFindMatch" | "JoinAsSpectator" | "TransferHost" | "LockLobby" | "UnlockLobby" | "QueueForLobby" | "LeaveLobbyQueue" | "RejoinLobby" | "GetLobbyEvents" | "InvitePlayer" | "AcceptInvitation" | "DeclineInvitation" | "RequestSync" | "Connected" | "LobbyCreated" | "LobbyJoined" | "CapacityUpdated" | "ConnectionQuality" | "PlayerReadyChanged" | "GameStarted" | "KickedFromLobby" | "PlayerKicked" | "LobbySettingsUpdated" | "MatchmakingQueued" | "MatchmakingCancelled" | "MatchmakingTimedOut" | "MatchFound" | "SpectatorJoined" | "HostChanged" | "LobbyClosed" | "LobbyLockChanged" | "PlayerLeft" | "LobbyQueuePosition" | "PlayerDisconnected" | "PlayerTimedOut" | "PlayerReconnected" | "SpectatorsChanged" | "LobbyEvents" | "AutoStartCountdown" | "AutoStartCancelled" | "InvitationReceived" | "InvitationUpdated" | "SyncState" | "LobbyStateDelta" | "UpgradeRequired";
+
+export type ErrorCode = "INTERNAL_ERROR" | "INVALID_REQUEST" | "UNKNOWN_MESSAGE_TYPE" | "UNSUPPORTED_PROTOCOL_VERSION" | "RATE_LIMITED" | "MESSAGE_TOO_LARGE" | "INVALID_NICKNAME" | "INVALID_AVATAR" | "SERVER_AT_CAPACITY" | "NICKNAME_RESERVED" | "ALREADY_IN_LOBBY" | "NOT_IN_LOBBY" | "NOT_HOST" | "LOBBY_NOT_FOUND" | "LOBBY_FULL" | "LOBBY_LOCKED" | "LOBBY_CODE_TAKEN" | "INVALID_LOBBY_CODE" | "INVALID_LOBBY_INFO" | "INVALID_SETTINGS" | "BANNED" | "PLAYER_NOT_FOUND" | "GAME_IN_PROGRESS" | "NOT_ENOUGH_PLAYERS" | "PLAYERS_NOT_READY" | "SPECTATOR_ACTION" | "SPECTATORS_DISABLED" | "NOT_QUEUED" | "INVALID_INVITE_TOKEN" | "INVITATION_NOT_FOUND" | "INVALID_RESUME_TOKEN" | "SEAT_NOT_RESERVED" | "AMBIGUOUS_NICKNAME" | "PLAYER_NOT_INVITABLE" | "PLAYER_DISCONNECTED";
+
+export type GameMode = "Classic";
+
+export type LoadTier = "Low" | "Medium" | "High" | "Full";
+
+export type LobbyEventType = "Created" | "PlayerJoined" | "SpectatorJoined" | "PlayerLeft" | "PlayerKicked" | "PlayerDisconnected" | "PlayerTimedOut" | "PlayerReconnected" | "HostChanged" | "SettingsChanged" | "LockChanged" | "GameStarted";
+
+export type InvitationStatus = "Pending" | "Accepted" | "Declined" | "Expired";